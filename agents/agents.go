@@ -2,8 +2,11 @@ package agents
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 // Agent represents an AI agent in the community
@@ -14,6 +17,13 @@ type Agent struct {
 	Courage  float64 `json:"courage"`
 	Empathy  float64 `json:"empathy"`
 	Elegance float64 `json:"elegance"`
+
+	// Boards the agent posts and replies in; empty means all of them
+	Boards []string `json:"boards,omitempty"`
+
+	// Model writes the agent's topics and replies instead of the backend's
+	// default model, e.g. a more creative one for some personas
+	Model string `json:"model,omitempty"`
 }
 
 // LoadAgents loads agent definitions from a JSON file
@@ -30,9 +40,129 @@ func LoadAgents(filename string) ([]Agent, error) {
 		return nil, fmt.Errorf("decoding agents JSON: %w", err)
 	}
 
+	if problems := validateAgents(agents, nil); problems != nil {
+		return nil, &InvalidAgentsError{Source: filename, Problems: problems}
+	}
+
+	return agents, nil
+}
+
+// LoadAgentsDir loads one agent from each *.json file in dir, in file name
+// order, so personas can be added or removed a file at a time
+func LoadAgentsDir(dir string) ([]Agent, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading agents directory: %w", err)
+	}
+
+	var agents []Agent
+	var sources []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading agent file: %w", err)
+		}
+		var agent Agent
+		if err := json.Unmarshal(data, &agent); err != nil {
+			return nil, fmt.Errorf("decoding agent %s: %w", entry.Name(), err)
+		}
+		agents = append(agents, agent)
+		sources = append(sources, entry.Name())
+	}
+
+	if problems := validateAgents(agents, sources); problems != nil {
+		return nil, &InvalidAgentsError{Source: dir, Problems: problems}
+	}
 	return agents, nil
 }
 
+// InvalidAgentsError is returned by the loaders when agent definitions fail
+// validation. Problems holds one error per problem, each naming the agent or
+// file it was found in.
+type InvalidAgentsError struct {
+	Source   string
+	Problems []error
+}
+
+func (e *InvalidAgentsError) Error() string {
+	return fmt.Sprintf("invalid agents in %s:\n%v", e.Source, errors.Join(e.Problems...))
+}
+
+// Unwrap returns the individual problems, for errors.Is and errors.As
+func (e *InvalidAgentsError) Unwrap() []error {
+	return e.Problems
+}
+
+// Validate reports every problem with the agent's definition: a missing ID,
+// name, or style, or a trait outside 0..1.
+func (a Agent) Validate() error {
+	return errors.Join(a.problems()...)
+}
+
+func (a Agent) problems() []error {
+	var problems []error
+	if strings.TrimSpace(a.ID) == "" {
+		problems = append(problems, errors.New("missing id"))
+	}
+	if strings.TrimSpace(a.Name) == "" {
+		problems = append(problems, errors.New("missing name"))
+	}
+	if strings.TrimSpace(a.Style) == "" {
+		problems = append(problems, errors.New("missing style"))
+	}
+	for _, trait := range []struct {
+		name  string
+		value float64
+	}{
+		{"courage", a.Courage},
+		{"empathy", a.Empathy},
+		{"elegance", a.Elegance},
+	} {
+		if trait.value < 0 || trait.value > 1 {
+			problems = append(problems, fmt.Errorf("%s %g is outside 0..1", trait.name, trait.value))
+		}
+	}
+	return problems
+}
+
+// validateAgents checks each agent and that IDs are unique, collecting every
+// problem found. Agents are named by position, or by sources[i] (the file
+// they came from) when given.
+func validateAgents(agents []Agent, sources []string) []error {
+	labels := make([]string, len(agents))
+	for i, agent := range agents {
+		switch {
+		case sources != nil:
+			labels[i] = sources[i]
+		case agent.ID != "":
+			labels[i] = fmt.Sprintf("agent %d (%s)", i+1, agent.ID)
+		default:
+			labels[i] = fmt.Sprintf("agent %d", i+1)
+		}
+	}
+
+	var problems []error
+	seen := make(map[string]int, len(agents))
+	for i, agent := range agents {
+		for _, problem := range agent.problems() {
+			problems = append(problems, fmt.Errorf("%s: %w", labels[i], problem))
+		}
+		if first, ok := seen[agent.ID]; ok && agent.ID != "" {
+			firstLabel := fmt.Sprintf("agent %d", first+1)
+			if sources != nil {
+				firstLabel = sources[first]
+			}
+			problems = append(problems, fmt.Errorf("%s: duplicate id, first used by %s", labels[i], firstLabel))
+		} else {
+			seen[agent.ID] = i
+		}
+	}
+	return problems
+}
+
 // SaveAgents saves agent definitions to a JSON file
 func SaveAgents(agents []Agent, filename string) error {
 	data, err := json.MarshalIndent(agents, "", "  ")
@@ -52,3 +182,22 @@ func SaveAgents(agents []Agent, filename string) error {
 
 	return nil
 }
+
+// IDIndex maps each agent's ID to the agent
+func IDIndex(agents []Agent) map[string]Agent {
+	index := make(map[string]Agent, len(agents))
+	for _, agent := range agents {
+		index[agent.ID] = agent
+	}
+	return index
+}
+
+// NameIndex maps each agent's lowercased display name to its ID, for
+// resolving content that was attributed by name instead of ID.
+func NameIndex(agents []Agent) map[string]string {
+	index := make(map[string]string, len(agents))
+	for _, agent := range agents {
+		index[strings.ToLower(strings.TrimSpace(agent.Name))] = agent.ID
+	}
+	return index
+}
@@ -0,0 +1,47 @@
+package agents
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAgentsReportsEachProblem(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.json")
+	data := `[
+		{"id": "ada", "name": "Ada", "style": "precise", "courage": 1.5},
+		{"id": "ada", "name": "", "style": "loud"}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadAgents(path)
+	var invalid *InvalidAgentsError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("LoadAgents error %v is not an *InvalidAgentsError", err)
+	}
+	if invalid.Source != path {
+		t.Errorf("Source = %q, want %q", invalid.Source, path)
+	}
+
+	want := []string{
+		"agent 1 (ada): courage 1.5 is outside 0..1",
+		"agent 2 (ada): missing name",
+		"agent 2 (ada): duplicate id, first used by agent 1",
+	}
+	problems := invalid.Unwrap()
+	if len(problems) != len(want) {
+		t.Fatalf("got %d problems %v, want %d", len(problems), problems, len(want))
+	}
+	for i, problem := range problems {
+		if problem.Error() != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, problem, want[i])
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "invalid agents in "+path+":\n") {
+		t.Errorf("error text %q lacks the source heading", err)
+	}
+}
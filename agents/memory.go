@@ -0,0 +1,125 @@
+package agents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Memory remembers the topics each agent recently created or replied to,
+// most recent first, so agents can spread out across threads. Topics are
+// identified by their path relative to the community directory.
+type Memory struct {
+	mu      sync.Mutex
+	limit   int
+	history map[string][]string
+}
+
+// NewMemory returns an empty memory keeping up to limit topics per agent
+func NewMemory(limit int) *Memory {
+	return &Memory{limit: limit, history: make(map[string][]string)}
+}
+
+// LoadMemory reads a memory saved by Save. A missing file yields an empty
+// memory.
+func LoadMemory(filename string, limit int) (*Memory, error) {
+	m := NewMemory(limit)
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading memory file: %w", err)
+	}
+	if err := json.Unmarshal(data, &m.history); err != nil {
+		return nil, fmt.Errorf("decoding memory JSON: %w", err)
+	}
+	if m.history == nil {
+		m.history = make(map[string][]string)
+	}
+
+	for id, topics := range m.history {
+		if len(topics) > limit {
+			m.history[id] = topics[:limit]
+		}
+	}
+	return m, nil
+}
+
+// Save writes the memory to filename as JSON
+func (m *Memory) Save(filename string) error {
+	// Held through the write so concurrent saves don't share the temp file
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := json.MarshalIndent(m.history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling memory: %w", err)
+	}
+
+	// Atomic write
+	tempFile := filename + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+
+	if err := os.Rename(tempFile, filename); err != nil {
+		return fmt.Errorf("renaming temp file: %w", err)
+	}
+
+	return nil
+}
+
+// Record notes that agentID just touched topic, forgetting the oldest topic
+// beyond the limit.
+func (m *Memory) Record(agentID, topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.limit <= 0 {
+		return
+	}
+
+	recent := []string{topic}
+	for _, t := range m.history[agentID] {
+		if t != topic && len(recent) < m.limit {
+			recent = append(recent, t)
+		}
+	}
+	m.history[agentID] = recent
+}
+
+// Touched reports whether agentID recently created or replied to topic
+func (m *Memory) Touched(agentID, topic string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.history[agentID] {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// RenameTopics replaces remembered topic paths found in renames with their
+// new paths. It reports whether anything changed.
+func (m *Memory) RenameTopics(renames map[string]string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changed := false
+	for _, topics := range m.history {
+		for i, t := range topics {
+			if renamed, ok := renames[t]; ok {
+				topics[i] = renamed
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kommunity/agents"
+	"kommunity/community"
+)
+
+// apiTopicSummary is a topic as listed by GET /api/topics
+type apiTopicSummary struct {
+	ID         string   `json:"id"`
+	Path       string   `json:"path"`
+	Title      string   `json:"title"`
+	Author     string   `json:"author"`
+	Timestamp  string   `json:"timestamp"`
+	EditedAt   string   `json:"edited_at,omitempty"`
+	Board      string   `json:"board"`
+	Tags       []string `json:"tags"`
+	Upvotes    int      `json:"upvotes"`
+	Downvotes  int      `json:"downvotes"`
+	ReplyCount int      `json:"reply_count"`
+}
+
+// apiTopic is a full topic with its replies, as returned by the API
+type apiTopic struct {
+	Path string `json:"path"`
+	community.Topic
+}
+
+// newTopicRequest is the body accepted by POST /api/topics
+type newTopicRequest struct {
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	Author string   `json:"author"`
+	Board  string   `json:"board"`
+	Tags   []string `json:"tags"`
+}
+
+// bulkReply is one entry of a scripted conversation posted to the bulk
+// reply endpoint.
+type bulkReply struct {
+	Author  string `json:"author"`
+	Content string `json:"content"`
+}
+
+// registerAPIRoutes adds the JSON endpoints under /api. Topics are addressed
+// by ID or by their path relative to the community directory, as in the HTML
+// topic URLs.
+func registerAPIRoutes(routes *gin.RouterGroup, opts serverOptions, agentList []agents.Agent) {
+	aliases := agents.NameIndex(agentList)
+	agentsByID := agents.IDIndex(agentList)
+	communityDir := opts.data.community()
+
+	api := routes.Group("/api")
+	if len(opts.corsOrigins) > 0 {
+		api.Use(allowOrigins(opts.corsOrigins))
+		// Preflights match no other route, so answer them here; the
+		// middleware has already written the response
+		api.OPTIONS("/*path", func(*gin.Context) {})
+	}
+
+	// The export is streamed file by file, so errors after the first topic
+	// can only be logged
+	api.GET("/export", func(c *gin.Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="community.ndjson"`)
+		c.Status(http.StatusOK)
+		if _, err := community.ExportTopics(communityDir, c.Writer); err != nil {
+			log.Printf("web: exporting topics: %v", err)
+		}
+	})
+
+	// Agents are the ones loaded at startup, in definition order
+	api.GET("/agents", func(c *gin.Context) {
+		list := agentList
+		if list == nil {
+			list = []agents.Agent{}
+		}
+		c.JSON(http.StatusOK, gin.H{"agents": list, "total": len(list)})
+	})
+
+	api.GET("/agents/:id", func(c *gin.Context) {
+		agent, ok := agentsByID[c.Param("id")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("agent not found: %s", c.Param("id"))})
+			return
+		}
+		c.JSON(http.StatusOK, agent)
+	})
+
+	api.GET("/topics", func(c *gin.Context) {
+		topics, err := community.LoadTopics(communityDir)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("loading topics: %v", err)})
+			return
+		}
+
+		summaries := make([]apiTopicSummary, 0, len(topics))
+		for _, t := range topics {
+			community.NormalizeAuthors(&t, aliases)
+			summaries = append(summaries, apiTopicSummary{
+				ID:         t.ID,
+				Path:       filepath.ToSlash(t.Filename),
+				Title:      t.Title,
+				Author:     t.Author,
+				Timestamp:  t.Timestamp,
+				EditedAt:   t.EditedAt,
+				Board:      community.BoardName(t.Board),
+				Tags:       t.Tags,
+				Upvotes:    t.Upvotes,
+				Downvotes:  t.Downvotes,
+				ReplyCount: len(t.Replies),
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"topics": summaries, "total": len(summaries)})
+	})
+
+	api.GET("/topics/*topicPath", func(c *gin.Context) {
+		rel := strings.TrimPrefix(c.Param("topicPath"), "/")
+		if rel == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "topic path is required"})
+			return
+		}
+
+		topic, err := loadTopicRef(communityDir, rel)
+		if err != nil {
+			topicError(c, rel, err, "loading topic")
+			return
+		}
+		community.NormalizeAuthors(&topic, aliases)
+
+		c.JSON(http.StatusOK, apiTopic{Path: filepath.ToSlash(topic.Filename), Topic: topic})
+	})
+
+	api.POST("/topics", limitBody(opts.maxBodyBytes), func(c *gin.Context) {
+		var req newTopicRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(bodyErrorStatus(err), gin.H{"error": fmt.Sprintf("invalid request body: %v", err)})
+			return
+		}
+
+		topic := community.Topic{
+			Title:     strings.TrimSpace(req.Title),
+			Body:      strings.TrimSpace(req.Body),
+			Author:    strings.TrimSpace(req.Author),
+			Timestamp: time.Now().Format(time.RFC3339),
+			Board:     strings.ToLower(strings.TrimSpace(req.Board)),
+			Tags:      req.Tags,
+			Replies:   []community.Reply{},
+		}
+		if topic.Title == "" || topic.Body == "" || topic.Author == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "title, body, and author are required"})
+			return
+		}
+		if topic.Tags == nil {
+			topic.Tags = []string{}
+		}
+		if err := checkLength("title", topic.Title, maxTitleLength); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := checkLength("body", topic.Body, maxContentLength); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		saved, err := community.CreateTopic(communityDir, topic)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("saving topic: %v", err)})
+			return
+		}
+
+		c.Header("Location", c.Request.URL.Path+"/"+saved.ID)
+		c.JSON(http.StatusCreated, apiTopic{Path: filepath.ToSlash(saved.Filename), Topic: saved})
+	})
+
+	api.POST("/topics/*topicPath", limitBody(opts.maxBodyBytes), func(c *gin.Context) {
+		rel := strings.TrimPrefix(c.Param("topicPath"), "/")
+		if !strings.HasSuffix(rel, "/replies/bulk") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		rel = strings.TrimSuffix(rel, "/replies/bulk")
+
+		var entries []bulkReply
+		if err := c.ShouldBindJSON(&entries); err != nil {
+			c.JSON(bodyErrorStatus(err), gin.H{"error": fmt.Sprintf("invalid request body: %v", err)})
+			return
+		}
+		if len(entries) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at least one reply is required"})
+			return
+		}
+
+		// Space timestamps a millisecond apart so the replies keep their order
+		start := time.Now()
+		replies := make([]community.Reply, 0, len(entries))
+		for i, entry := range entries {
+			author := strings.TrimSpace(entry.Author)
+			content := strings.TrimSpace(entry.Content)
+			if author == "" || content == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reply %d: author and content are required", i)})
+				return
+			}
+			if err := checkLength("content", content, maxContentLength); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("reply %d: %v", i, err)})
+				return
+			}
+			replies = append(replies, community.Reply{
+				Author:    author,
+				Content:   content,
+				Timestamp: start.Add(time.Duration(i) * time.Millisecond).Format(time.RFC3339Nano),
+			})
+		}
+
+		if !isTopicPath(rel) {
+			topic, err := community.LoadTopicByID(communityDir, rel)
+			if err != nil {
+				topicError(c, rel, err, "loading topic")
+				return
+			}
+			rel = filepath.ToSlash(topic.Filename)
+		}
+
+		if err := community.AddRepliesByPath(communityDir, rel, replies); err != nil {
+			topicError(c, rel, err, "adding replies")
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"path": rel, "added": len(replies)})
+	})
+}
+
+// topicError reports a failed topic lookup or update as JSON: 404 when the
+// topic does not exist, 400 for a path outside the community directory, and
+// 500 otherwise.
+func topicError(c *gin.Context, rel string, err error, action string) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("topic not found: %s", rel)})
+	case errors.Is(err, community.ErrInvalidTopicPath):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s: %v", action, err)})
+	}
+}
+
+// corsMaxAge is how long browsers may cache a preflight answer
+const corsMaxAge = 10 * time.Minute
+
+// allowOrigins adds CORS headers for requests from the given origins ("*"
+// allows any) and answers preflight requests. Requests from other origins get
+// no CORS headers, so browsers keep blocking them.
+func allowOrigins(origins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		c.Header("Vary", "Origin")
+		if origin == "" || !originAllowed(origins, origin) {
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Content-Type")
+			c.Header("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Header("Access-Control-Expose-Headers", "Location")
+		c.Next()
+	}
+}
+
+// originAllowed reports whether origin is one of the configured origins
+func originAllowed(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOrigins splits a comma-separated -cors-origin value, dropping blanks
+// and trailing slashes so "https://app.example.com/" matches the Origin header
+func parseOrigins(raw string) []string {
+	var origins []string
+	for _, o := range strings.Split(raw, ",") {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kommunity/community"
+)
+
+func TestBulkRepliesLandInOrder(t *testing.T) {
+	router, paths := newTestRouter(t, serverOptions{maxBodyBytes: 1 << 20})
+	topic, err := community.CreateTopic(paths.community(), community.Topic{Title: "Scripted", Body: "A demo", Author: "alice", Timestamp: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []string
+	for i := 1; i <= 5; i++ {
+		entries = append(entries, fmt.Sprintf(`{"author": "agent_%d", "content": "line %d"}`, i, i))
+	}
+	body := "[" + strings.Join(entries, ",") + "]"
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/topics/"+topic.ID+"/replies/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST = %d %s, want 201", rec.Code, rec.Body)
+	}
+
+	saved, err := community.LoadTopicByID(paths.community(), topic.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Replies) != 5 {
+		t.Fatalf("saved %d replies, want 5", len(saved.Replies))
+	}
+	var previous time.Time
+	for i, reply := range saved.Replies {
+		if want := fmt.Sprintf("line %d", i+1); reply.Content != want {
+			t.Errorf("reply %d = %q, want %q", i, reply.Content, want)
+		}
+		ts, err := community.ParseTimestamp(reply.Timestamp)
+		if err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		}
+		if i > 0 && !ts.After(previous) {
+			t.Errorf("reply %d timestamp %s is not after %s", i, ts, previous)
+		}
+		previous = ts
+	}
+}
+
+func TestBulkRepliesRejectsInvalidEntries(t *testing.T) {
+	router, paths := newTestRouter(t, serverOptions{maxBodyBytes: 1 << 20})
+	topic, err := community.CreateTopic(paths.community(), community.Topic{Title: "Scripted", Body: "A demo", Author: "alice", Timestamp: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `[{"author": "alice", "content": "fine"}, {"author": "", "content": "anonymous"}]`
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/topics/"+topic.ID+"/replies/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("POST = %d, want 400", rec.Code)
+	}
+
+	saved, err := community.LoadTopicByID(paths.community(), topic.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Replies) != 0 {
+		t.Errorf("saved %d replies from a rejected batch, want none", len(saved.Replies))
+	}
+}
+
+// postJSON sends body to path as JSON
+func postJSON(router http.Handler, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateTopicRejectsOversizedBody(t *testing.T) {
+	router, paths := newTestRouter(t, serverOptions{maxBodyBytes: 1024})
+
+	body := fmt.Sprintf(`{"title": "Too big", "body": %q, "author": "alice"}`, strings.Repeat("x", 2048))
+	if rec := postJSON(router, "/api/topics", body); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("API POST = %d, want 413", rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/topics", strings.NewReader("title=Too+big&body="+strings.Repeat("x", 2048)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("form POST = %d, want 413", rec.Code)
+	}
+
+	if topics, _ := community.LoadTopics(paths.community()); len(topics) != 0 {
+		t.Errorf("%d topics saved, want none", len(topics))
+	}
+}
+
+func TestCreateTopicRejectsOverlongTitle(t *testing.T) {
+	router, paths := newTestRouter(t, serverOptions{maxBodyBytes: 1 << 20})
+
+	body := fmt.Sprintf(`{"title": %q, "body": "Fine", "author": "alice"}`, strings.Repeat("t", maxTitleLength+1))
+	rec := postJSON(router, "/api/topics", body)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST = %d, want 400", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "title") {
+		t.Errorf("error %s doesn't name the title", rec.Body)
+	}
+
+	if topics, _ := community.LoadTopics(paths.community()); len(topics) != 0 {
+		t.Errorf("%d topics saved, want none", len(topics))
+	}
+}
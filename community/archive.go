@@ -0,0 +1,129 @@
+package community
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// evictMu keeps concurrent evictions from picking the same topics
+var evictMu sync.Mutex
+
+// EvictToLimit archives the least recently active topics, as ArchiveTopic
+// does, until at most limit active ones remain. Pinned topics are never
+// archived, so the community may stay above the limit if too many are
+// pinned. It returns how many topics were archived.
+func EvictToLimit(dir string, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	evictMu.Lock()
+	defer evictMu.Unlock()
+
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		return 0, err
+	}
+	excess := len(topics) - limit
+	if excess <= 0 {
+		return 0, nil
+	}
+
+	candidates := make([]Topic, 0, len(topics))
+	for _, t := range topics {
+		if !t.Pinned {
+			candidates = append(candidates, t)
+		}
+	}
+
+	// Oldest activity first; among equally stale topics, the quietest goes first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ai, aj := ActivityTime(candidates[i]), ActivityTime(candidates[j])
+		if !ai.Equal(aj) {
+			return ai.Before(aj)
+		}
+		return len(candidates[i].Replies) < len(candidates[j].Replies)
+	})
+
+	archived := 0
+	for _, t := range candidates[:min(excess, len(candidates))] {
+		if err := setArchived(dir, t.Filename, true); err != nil {
+			return archived, fmt.Errorf("archiving %s: %w", t.Filename, err)
+		}
+		archived++
+	}
+	return archived, nil
+}
+
+// ErrTopicArchived is returned when replying to an archived topic
+var ErrTopicArchived = errors.New("topic is archived")
+
+// ArchiveTopic marks the topic at relPath as archived. It keeps its file and
+// links, but LoadTopics and the listings built on it leave it out, and it
+// takes no new replies. Archiving an archived topic changes nothing.
+func ArchiveTopic(dir, relPath string) error {
+	return setArchived(dir, relPath, true)
+}
+
+// UnarchiveTopic returns an archived topic to the community
+func UnarchiveTopic(dir, relPath string) error {
+	return setArchived(dir, relPath, false)
+}
+
+func setArchived(dir, relPath string, archived bool) error {
+	_, err := modifyTopic(dir, relPath, func(t *Topic) error {
+		t.Archived = archived
+		return nil
+	})
+	return err
+}
+
+// LoadArchivedTopics returns the archived topics, newest first
+func LoadArchivedTopics(dir string) ([]Topic, error) {
+	topics, err := LoadAllTopics(dir)
+	if err != nil {
+		return nil, err
+	}
+	archived := []Topic{}
+	for _, t := range topics {
+		if t.Archived {
+			archived = append(archived, t)
+		}
+	}
+	return archived, nil
+}
+
+// activeTopics filters out archived topics in place
+func activeTopics(topics []Topic) []Topic {
+	active := topics[:0]
+	for _, t := range topics {
+		if !t.Archived {
+			active = append(active, t)
+		}
+	}
+	return active
+}
+
+// lastActivity returns the most recent timestamp on a topic, considering the
+// topic itself and all of its replies.
+func lastActivity(t Topic) string {
+	latest := t.Timestamp
+	latestTime, _ := ParseTimestamp(t.Timestamp)
+	for _, reply := range t.Replies {
+		parsed, err := ParseTimestamp(reply.Timestamp)
+		if err == nil && parsed.After(latestTime) {
+			latest, latestTime = reply.Timestamp, parsed
+		}
+	}
+	return latest
+}
+
+// ActivityTime is when a topic or its latest reply was posted; unparseable
+// timestamps count as the oldest
+func ActivityTime(t Topic) time.Time {
+	parsed, _ := ParseTimestamp(lastActivity(t))
+	return parsed
+}
@@ -0,0 +1,77 @@
+package community
+
+import "testing"
+
+func TestEvictToLimitArchivesOldestUnpinnedTopics(t *testing.T) {
+	dir := t.TempDir()
+	writeTopicFile(t, dir, "oldest.json", Topic{Title: "Oldest", Timestamp: "2024-01-01T00:00:00Z"})
+	writeTopicFile(t, dir, "pinned.json", Topic{Title: "Pinned", Timestamp: "2023-01-01T00:00:00Z", Pinned: true})
+	writeTopicFile(t, dir, "revived.json", Topic{
+		Title:     "Revived",
+		Timestamp: "2023-06-01T00:00:00Z",
+		Replies:   []Reply{{Author: "bob", Content: "bump", Timestamp: "2024-06-01T00:00:00Z"}},
+	})
+	writeTopicFile(t, dir, "older.json", Topic{Title: "Older", Timestamp: "2024-02-01T00:00:00Z"})
+	writeTopicFile(t, dir, "newest.json", Topic{Title: "Newest", Timestamp: "2024-07-01T00:00:00Z"})
+
+	archived, err := EvictToLimit(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived != 2 {
+		t.Fatalf("archived %d topics, want 2", archived)
+	}
+
+	archivedTopics, err := LoadArchivedTopics(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evicted := make(map[string]bool)
+	for _, topic := range archivedTopics {
+		evicted[topic.Filename] = true
+	}
+	for _, name := range []string{"oldest.json", "older.json"} {
+		if !evicted[name] {
+			t.Errorf("%s is not archived", name)
+		}
+	}
+
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := make(map[string]bool)
+	for _, topic := range topics {
+		remaining[topic.Filename] = true
+	}
+	for _, name := range []string{"pinned.json", "revived.json", "newest.json"} {
+		if !remaining[name] {
+			t.Errorf("%s was evicted, want it kept", name)
+		}
+	}
+	if len(topics) != 3 {
+		t.Errorf("%d topics remain, want 3", len(topics))
+	}
+}
+
+func TestEvictToLimitKeepsPinnedTopicsOverLimit(t *testing.T) {
+	dir := t.TempDir()
+	writeTopicFile(t, dir, "a.json", Topic{Title: "A", Timestamp: "2024-01-01T00:00:00Z", Pinned: true})
+	writeTopicFile(t, dir, "b.json", Topic{Title: "B", Timestamp: "2024-02-01T00:00:00Z", Pinned: true})
+	writeTopicFile(t, dir, "c.json", Topic{Title: "C", Timestamp: "2024-03-01T00:00:00Z"})
+
+	archived, err := EvictToLimit(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived != 1 {
+		t.Errorf("archived %d topics, want only the unpinned one", archived)
+	}
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 {
+		t.Errorf("%d topics remain, want the 2 pinned ones", len(topics))
+	}
+}
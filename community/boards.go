@@ -0,0 +1,46 @@
+package community
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultBoard holds topics that weren't filed under any board, including
+// every topic written before boards existed
+const DefaultBoard = "general"
+
+// BoardName normalizes a board as stored on a topic: trimmed, lowercased, and
+// DefaultBoard when empty
+func BoardName(board string) string {
+	board = strings.ToLower(strings.TrimSpace(board))
+	if board == "" {
+		return DefaultBoard
+	}
+	return board
+}
+
+// BoardCount is one board and how many topics it holds
+type BoardCount struct {
+	Name   string
+	Topics int
+}
+
+// CountBoards tallies topics per board, busiest first and then by name
+func CountBoards(topics []Topic) []BoardCount {
+	counts := make(map[string]int)
+	for _, t := range topics {
+		counts[BoardName(t.Board)]++
+	}
+
+	boards := make([]BoardCount, 0, len(counts))
+	for name, n := range counts {
+		boards = append(boards, BoardCount{Name: name, Topics: n})
+	}
+	sort.Slice(boards, func(i, j int) bool {
+		if boards[i].Topics != boards[j].Topics {
+			return boards[i].Topics > boards[j].Topics
+		}
+		return boards[i].Name < boards[j].Name
+	})
+	return boards
+}
@@ -0,0 +1,94 @@
+package community
+
+import (
+	"strings"
+	"unicode"
+)
+
+// dedupWindow is how many of the newest topics a new title is compared with
+const dedupWindow = 50
+
+// IsDuplicateTopic reports whether title is at least threshold similar (0 to
+// 1) to the title of one of the most recent topics. Titles are compared
+// ignoring case, punctuation, and spacing, taking the higher of their edit
+// distance ratio and word overlap.
+func IsDuplicateTopic(dir, title string, threshold float64) (bool, error) {
+	topics, err := LoadRecentTopics(dir, dedupWindow)
+	if err != nil {
+		return false, err
+	}
+
+	normalized := normalizeTitle(title)
+	for _, topic := range topics {
+		if titleSimilarity(normalized, normalizeTitle(topic.Title)) >= threshold {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// normalizeTitle lowercases s and reduces it to words separated by single
+// spaces
+func normalizeTitle(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// titleSimilarity scores two normalized titles from 0 (unrelated) to 1
+// (identical)
+func titleSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	return max(levenshteinRatio(a, b), wordOverlap(a, b))
+}
+
+// levenshteinRatio is 1 minus the edit distance over the longer length
+func levenshteinRatio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// wordOverlap is the Jaccard index of the two titles' word sets
+func wordOverlap(a, b string) float64 {
+	wordsA, wordsB := make(map[string]bool), make(map[string]bool)
+	for _, w := range strings.Fields(a) {
+		wordsA[w] = true
+	}
+	for _, w := range strings.Fields(b) {
+		wordsB[w] = true
+	}
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
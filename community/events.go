@@ -0,0 +1,74 @@
+package community
+
+import (
+	"sync"
+
+	"kommunity/metrics"
+)
+
+// Event types published when the community changes
+const (
+	EventTopicCreated = "topic"
+	EventReplyAdded   = "reply"
+	EventTopicDeleted = "delete"
+)
+
+// Event describes a topic or reply that was just persisted, or a topic that
+// was removed
+type Event struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Path      string `json:"path"`
+	Author    string `json:"author"`
+	Content   string `json:"content,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// subscriberBuffer bounds how far a slow subscriber may fall behind before
+// events are dropped for it.
+const subscriberBuffer = 32
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = make(map[chan Event]struct{})
+)
+
+// Subscribe registers a listener for community events. The returned function
+// unregisters the listener and must be called once the caller is done.
+func Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	subscribersMu.Lock()
+	subscribers[ch] = struct{}{}
+	subscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			subscribersMu.Lock()
+			delete(subscribers, ch)
+			subscribersMu.Unlock()
+		})
+	}
+}
+
+// publish counts an event and fans it out to every subscriber without
+// blocking the writer
+func publish(ev Event) {
+	switch ev.Type {
+	case EventTopicCreated:
+		metrics.TopicsCreated.Inc()
+	case EventReplyAdded:
+		metrics.RepliesAdded.Inc()
+	}
+
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	for ch := range subscribers {
+		select {
+		case ch <- ev:
+		default: // subscriber is not keeping up; drop rather than stall saves
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package community
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// exportRecord is one line of an NDJSON export: a topic with its replies and
+// the path it is stored at, relative to the community directory
+type exportRecord struct {
+	Path string `json:"path"`
+	Topic
+}
+
+// ExportTopics writes every topic under dir to w as NDJSON, one topic per
+// line, reading one file at a time so large communities never sit in memory
+// whole. Unreadable topic files are skipped and logged, as LoadTopics does.
+// It returns the number of topics written.
+func ExportTopics(dir string, w io.Writer) (int, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, fmt.Errorf("resolving community directory: %w", err)
+	}
+	paths, err := topicFiles(absDir)
+	if err != nil {
+		return 0, err
+	}
+
+	enc := json.NewEncoder(w)
+	written := 0
+	for _, path := range paths {
+		topic, err := readTopicFile(absDir, path)
+		if err != nil {
+			continue
+		}
+		if err := enc.Encode(exportRecord{Path: filepath.ToSlash(topic.Filename), Topic: topic}); err != nil {
+			return written, fmt.Errorf("writing topic %s: %w", filepath.ToSlash(topic.Filename), err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+// ImportTopics reads an NDJSON export from r and saves each topic into dir
+// with SaveTopic, at its recorded path, replacing any topic already there.
+// Records without a path are saved as new topics. It returns the number of
+// topics imported before the first error.
+func ImportTopics(dir string, r io.Reader) (int, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, fmt.Errorf("resolving community directory: %w", err)
+	}
+
+	dec := json.NewDecoder(r)
+	imported := 0
+	for {
+		var record exportRecord
+		if err := dec.Decode(&record); errors.Is(err, io.EOF) {
+			return imported, nil
+		} else if err != nil {
+			return imported, fmt.Errorf("decoding topic %d: %w", imported+1, err)
+		}
+
+		topic := record.Topic
+		if record.Path == "" {
+			if _, err := CreateTopic(dir, topic); err != nil {
+				return imported, fmt.Errorf("saving topic %d: %w", imported+1, err)
+			}
+			imported++
+			continue
+		}
+
+		// Exports may come from anywhere, so paths get the same checks as URLs
+		if _, err := resolveTopicPath(absDir, filepath.FromSlash(record.Path)); err != nil {
+			return imported, fmt.Errorf("topic %d: %w", imported+1, err)
+		}
+		topic.Filename = filepath.FromSlash(record.Path)
+		if err := SaveTopic(topic, dir); err != nil {
+			return imported, fmt.Errorf("saving topic %s: %w", record.Path, err)
+		}
+		imported++
+	}
+}
@@ -0,0 +1,23 @@
+package community
+
+import "sync"
+
+var (
+	topicLocksMu sync.Mutex
+	topicLocks   = make(map[string]*sync.Mutex)
+)
+
+// lockTopic serializes load-modify-write sequences on a single topic file,
+// identified by its absolute path. Call the returned function to release it.
+func lockTopic(path string) func() {
+	topicLocksMu.Lock()
+	mu, ok := topicLocks[path]
+	if !ok {
+		mu = &sync.Mutex{}
+		topicLocks[path] = mu
+	}
+	topicLocksMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
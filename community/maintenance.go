@@ -0,0 +1,278 @@
+package community
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimestamp parses a stored topic or reply timestamp, accepting both
+// RFC3339 and RFC3339Nano values.
+func ParseTimestamp(ts string) (time.Time, error) {
+	if ts == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+	if parsed, err := time.Parse(time.RFC3339, ts); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unrecognized timestamp %q", ts)
+	}
+	return parsed, nil
+}
+
+// FixTimestamps backfills topics whose timestamp is empty or unparseable,
+// using the earliest valid reply timestamp or else the file's modification
+// time. With dryRun set nothing is written. It returns how many topics were
+// (or would be) fixed.
+func FixTimestamps(dir string, dryRun bool) (int, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, fmt.Errorf("resolving community directory: %w", err)
+	}
+
+	topics, err := LoadAllTopics(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	fixed := 0
+	for _, topic := range topics {
+		if _, err := ParseTimestamp(topic.Timestamp); err == nil {
+			continue
+		}
+
+		ts, err := derivedTimestamp(topic, filepath.Join(absDir, topic.Filename))
+		if err != nil {
+			return fixed, err
+		}
+
+		fmt.Printf("🕰️  %s: %q -> %s\n", filepath.ToSlash(topic.Filename), topic.Timestamp, ts)
+		fixed++
+		if dryRun {
+			continue
+		}
+
+		err = UpdateTopic(dir, topic.Filename, func(t *Topic) {
+			if _, err := ParseTimestamp(t.Timestamp); err != nil {
+				t.Timestamp = ts
+			}
+		})
+		if err != nil {
+			return fixed, fmt.Errorf("saving %s: %w", topic.Filename, err)
+		}
+	}
+
+	return fixed, nil
+}
+
+// derivedTimestamp picks a replacement timestamp for a topic: the earliest
+// parseable reply, falling back to the topic file's modification time.
+func derivedTimestamp(topic Topic, path string) (string, error) {
+	var earliest time.Time
+	for _, reply := range topic.Replies {
+		parsed, err := ParseTimestamp(reply.Timestamp)
+		if err != nil {
+			continue
+		}
+		if earliest.IsZero() || parsed.Before(earliest) {
+			earliest = parsed
+		}
+	}
+	if !earliest.IsZero() {
+		return earliest.Format(time.RFC3339), nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("reading topic file info: %w", err)
+	}
+	return info.ModTime().Format(time.RFC3339), nil
+}
+
+// NormalizeAuthor returns the agent ID for an author recorded by display
+// name, or author unchanged
+func NormalizeAuthor(author string, aliases map[string]string) string {
+	if id, ok := resolveAlias(author, aliases); ok {
+		return id
+	}
+	return author
+}
+
+// NormalizeAuthors rewrites topic and reply authors given as an agent display
+// name to that agent's ID. aliases maps lowercased names to IDs, as built by
+// agents.NameIndex. It reports whether anything changed.
+func NormalizeAuthors(topic *Topic, aliases map[string]string) bool {
+	changed := false
+	if id, ok := resolveAlias(topic.Author, aliases); ok {
+		topic.Author = id
+		changed = true
+	}
+	for i := range topic.Replies {
+		if id, ok := resolveAlias(topic.Replies[i].Author, aliases); ok {
+			topic.Replies[i].Author = id
+			changed = true
+		}
+	}
+	return changed
+}
+
+// MigrateAuthors applies NormalizeAuthors to every stored topic and rewrites
+// the ones that changed. With dryRun set nothing is written. It returns how
+// many topics were (or would be) updated.
+func MigrateAuthors(dir string, aliases map[string]string, dryRun bool) (int, error) {
+	topics, err := LoadAllTopics(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, topic := range topics {
+		if !NormalizeAuthors(&topic, aliases) {
+			continue
+		}
+
+		fmt.Printf("🪪 %s: normalized authors\n", filepath.ToSlash(topic.Filename))
+		updated++
+		if dryRun {
+			continue
+		}
+		err := UpdateTopic(dir, topic.Filename, func(t *Topic) {
+			NormalizeAuthors(t, aliases)
+		})
+		if err != nil {
+			return updated, fmt.Errorf("saving %s: %w", topic.Filename, err)
+		}
+	}
+
+	return updated, nil
+}
+
+// MigrateFilenames renames topic files to the current naming scheme, a
+// creation time prefix followed by a slug of the title, keeping each file in
+// its directory. The topic is rewritten under the new name with its ID
+// stored, so links keep resolving, and the old file is removed. Topics with
+// an unparseable timestamp are skipped; run FixTimestamps first. With dryRun
+// set nothing is written. It returns the old and new relative paths (with
+// forward slashes) of every topic that was (or would be) renamed.
+func MigrateFilenames(dir string, dryRun bool) (map[string]string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving community directory: %w", err)
+	}
+
+	topics, err := LoadAllTopics(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		taken[filepath.ToSlash(topic.Filename)] = true
+	}
+
+	renames := make(map[string]string)
+	for _, topic := range topics {
+		oldRel := filepath.ToSlash(topic.Filename)
+		if _, err := ParseTimestamp(topic.Timestamp); err != nil {
+			fmt.Printf("📛 %s: skipped, timestamp %q is not valid (see -fix-timestamps)\n", oldRel, topic.Timestamp)
+			continue
+		}
+
+		name := topicFilename(topic)
+		if isFilenameFor(filepath.Base(topic.Filename), name) {
+			continue
+		}
+		subdir := filepath.Dir(topic.Filename)
+
+		var newRel string
+		if dryRun {
+			newRel = planFilename(taken, subdir, name)
+		} else {
+			newRel, err = renameTopicFile(absDir, topic, subdir, name)
+			if err != nil {
+				return renames, fmt.Errorf("renaming %s: %w", oldRel, err)
+			}
+		}
+
+		fmt.Printf("📛 %s -> %s\n", oldRel, newRel)
+		renames[oldRel] = newRel
+	}
+
+	return renames, nil
+}
+
+// isFilenameFor reports whether current is name, possibly with the "_2",
+// "_3", ... suffix added when a name is already taken
+func isFilenameFor(current, name string) bool {
+	if current == name {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(current, strings.TrimSuffix(name, ".json")+"_")
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(suffix, ".json"))
+	return err == nil && n > 1 && strings.HasSuffix(suffix, ".json")
+}
+
+// planFilename picks the name reserveTopicFile would choose for name in
+// subdir, given the relative paths already taken, and marks it taken
+func planFilename(taken map[string]bool, subdir, name string) string {
+	base := strings.TrimSuffix(name, ".json")
+	for n := 1; ; n++ {
+		candidate := name
+		if n > 1 {
+			candidate = fmt.Sprintf("%s_%d.json", base, n)
+		}
+		rel := filepath.ToSlash(filepath.Join(subdir, candidate))
+		if !taken[rel] {
+			taken[rel] = true
+			return rel
+		}
+	}
+}
+
+// renameTopicFile writes topic under a fresh file named after name in subdir
+// and removes its old file, returning the new relative path
+func renameTopicFile(absDir string, topic Topic, subdir, name string) (string, error) {
+	oldPath := filepath.Join(absDir, topic.Filename)
+	unlock := lockTopic(oldPath)
+	defer unlock()
+
+	// Re-read under the lock so a concurrent update isn't lost
+	current, err := loadTopic(oldPath)
+	if err != nil {
+		return "", err
+	}
+	ensureTopicID(&current, topic.Filename)
+
+	newPath, err := reserveTopicFile(filepath.Join(absDir, subdir), name)
+	if err != nil {
+		return "", err
+	}
+	current.Filename = newPath
+	saved, err := saveTopic(current, absDir)
+	if err != nil {
+		os.Remove(newPath)
+		return "", err
+	}
+
+	if err := os.Remove(oldPath); err != nil {
+		return "", fmt.Errorf("removing old topic file: %w", err)
+	}
+	invalidateStore(absDir)
+	return filepath.ToSlash(saved.Filename), nil
+}
+
+func resolveAlias(author string, aliases map[string]string) (string, bool) {
+	id, ok := aliases[strings.ToLower(strings.TrimSpace(author))]
+	if !ok || id == author {
+		return "", false
+	}
+	return id, true
+}
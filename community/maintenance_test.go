@@ -0,0 +1,123 @@
+package community
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTopicFile stores topic as name under dir without going through
+// SaveTopic, so fixtures can hold data SaveTopic would never write
+func writeTopicFile(t *testing.T, dir, name string, topic Topic) string {
+	t.Helper()
+	data, err := json.Marshal(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFixTimestampsBackfillsMissingTimestamps(t *testing.T) {
+	dir := t.TempDir()
+	writeTopicFile(t, dir, "replied.json", Topic{
+		Title: "Replied",
+		Replies: []Reply{
+			{Author: "bob", Content: "later", Timestamp: "2024-05-02T10:00:00Z"},
+			{Author: "carol", Content: "earlier", Timestamp: "2024-05-01T08:00:00Z"},
+		},
+	})
+	quiet := writeTopicFile(t, dir, "quiet.json", Topic{Title: "Quiet", Timestamp: "yesterday-ish"})
+	modTime := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(quiet, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	writeTopicFile(t, dir, "fine.json", Topic{Title: "Fine", Timestamp: "2024-05-03T09:00:00Z"})
+
+	// A dry run counts without writing
+	fixed, err := FixTimestamps(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed != 2 {
+		t.Errorf("dry run fixed %d topics, want 2", fixed)
+	}
+	if topic, err := LoadTopicByRelativePath(dir, "replied.json"); err != nil || topic.Timestamp != "" {
+		t.Fatalf("dry run wrote %q (%v)", topic.Timestamp, err)
+	}
+
+	if fixed, err = FixTimestamps(dir, false); err != nil || fixed != 2 {
+		t.Fatalf("FixTimestamps = %d, %v; want 2 fixed", fixed, err)
+	}
+	for name, want := range map[string]time.Time{
+		"replied.json": time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), // earliest reply
+		"quiet.json":   modTime,                                     // file modification time
+		"fine.json":    time.Date(2024, 5, 3, 9, 0, 0, 0, time.UTC), // untouched
+	} {
+		topic, err := LoadTopicByRelativePath(dir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ParseTimestamp(topic.Timestamp)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("%s: timestamp %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestMigrateAuthorsResolvesDisplayNames(t *testing.T) {
+	dir := t.TempDir()
+	aliases := map[string]string{"julia child": "julia", "gordon ramsay": "gordon"}
+	writeTopicFile(t, dir, "seed.json", Topic{
+		Title:     "Seeded by name",
+		Author:    "Julia Child",
+		Timestamp: "2024-05-01T08:00:00Z",
+		Replies: []Reply{
+			{Author: " gordon ramsay ", Content: "by name"},
+			{Author: "julia", Content: "already an ID"},
+			{Author: "visitor", Content: "not an agent"},
+		},
+	})
+
+	if got := NormalizeAuthor("GORDON RAMSAY", aliases); got != "gordon" {
+		t.Errorf("NormalizeAuthor = %q, want %q", got, "gordon")
+	}
+
+	updated, err := MigrateAuthors(dir, aliases, true)
+	if err != nil || updated != 1 {
+		t.Fatalf("dry run MigrateAuthors = %d, %v; want 1 updated", updated, err)
+	}
+	if topic, err := LoadTopicByRelativePath(dir, "seed.json"); err != nil || topic.Author != "Julia Child" {
+		t.Fatalf("dry run wrote author %q (%v)", topic.Author, err)
+	}
+
+	if updated, err = MigrateAuthors(dir, aliases, false); err != nil || updated != 1 {
+		t.Fatalf("MigrateAuthors = %d, %v; want 1 updated", updated, err)
+	}
+	topic, err := LoadTopicByRelativePath(dir, "seed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic.Author != "julia" {
+		t.Errorf("topic author = %q, want %q", topic.Author, "julia")
+	}
+	for i, want := range []string{"gordon", "julia", "visitor"} {
+		if got := topic.Replies[i].Author; got != want {
+			t.Errorf("reply %d author = %q, want %q", i, got, want)
+		}
+	}
+
+	// Normalized topics are left alone on the next run
+	if updated, err = MigrateAuthors(dir, aliases, false); err != nil || updated != 0 {
+		t.Errorf("second MigrateAuthors = %d, %v; want 0 updated", updated, err)
+	}
+}
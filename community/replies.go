@@ -0,0 +1,151 @@
+package community
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// ErrReplyNotFound is returned when a reply ID does not match any reply on
+// the topic.
+var ErrReplyNotFound = errors.New("reply not found")
+
+// ReplyNode is a reply together with the replies made to it
+type ReplyNode struct {
+	Reply
+	Children []*ReplyNode
+}
+
+// BuildReplyTree arranges replies into threads, keeping their stored order
+// among siblings. Replies without a parent, or whose parent is missing, are
+// returned at the top level.
+func BuildReplyTree(replies []Reply) []*ReplyNode {
+	nodes := make([]*ReplyNode, len(replies))
+	byID := make(map[string]*ReplyNode, len(replies))
+	for i, reply := range replies {
+		nodes[i] = &ReplyNode{Reply: reply}
+		if reply.ID != "" {
+			byID[reply.ID] = nodes[i]
+		}
+	}
+
+	var roots []*ReplyNode
+	for _, node := range nodes {
+		parent, ok := byID[node.ParentID]
+		if node.ParentID == "" || !ok || parent == node {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return roots
+}
+
+// AddReplyToReply appends reply to the topic stored at relPath as an answer
+// to the reply identified by parentID.
+func AddReplyToReply(dir, relPath, parentID string, reply Reply) error {
+	reply.ParentID = parentID
+	topic, err := modifyTopic(dir, relPath, func(t *Topic) error {
+		if t.Archived {
+			return fmt.Errorf("%w: %s", ErrTopicArchived, relPath)
+		}
+		if !hasReply(t, parentID) {
+			return fmt.Errorf("%w: %s", ErrReplyNotFound, parentID)
+		}
+		t.Replies = append(t.Replies, reply)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	publish(Event{
+		Type:      EventReplyAdded,
+		Title:     topic.Title,
+		Path:      filepath.ToSlash(filepath.Clean(relPath)),
+		Author:    reply.Author,
+		Content:   reply.Content,
+		Timestamp: reply.Timestamp,
+	})
+	return nil
+}
+
+// UpvoteReply adds an upvote to the reply at replyIndex, counting from 0 in
+// stored order, on the topic at topicPath
+func UpvoteReply(dir, topicPath string, replyIndex int) error {
+	return voteReply(dir, topicPath, replyIndex, 1, 0)
+}
+
+// DownvoteReply adds a downvote to the reply at replyIndex on the topic at
+// topicPath
+func DownvoteReply(dir, topicPath string, replyIndex int) error {
+	return voteReply(dir, topicPath, replyIndex, 0, 1)
+}
+
+func voteReply(dir, topicPath string, replyIndex, up, down int) error {
+	_, err := modifyTopic(dir, topicPath, func(t *Topic) error {
+		if replyIndex < 0 || replyIndex >= len(t.Replies) {
+			return fmt.Errorf("%w: index %d of %d", ErrReplyNotFound, replyIndex, len(t.Replies))
+		}
+		t.Replies[replyIndex].Upvotes += up
+		t.Replies[replyIndex].Downvotes += down
+		return nil
+	})
+	return err
+}
+
+// Score is the reply's upvotes minus its downvotes
+func (r Reply) Score() int {
+	return r.Upvotes - r.Downvotes
+}
+
+// SortReplyTree orders each level of a reply tree by score, highest first,
+// keeping stored order among equal scores
+func SortReplyTree(nodes []*ReplyNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Score() > nodes[j].Score()
+	})
+	for _, node := range nodes {
+		SortReplyTree(node.Children)
+	}
+}
+
+func hasReply(topic *Topic, id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, reply := range topic.Replies {
+		if reply.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// assignReplyIDs gives every reply that lacks one a random ID, so older
+// topics become addressable the next time they are saved.
+func assignReplyIDs(topic *Topic) error {
+	for i := range topic.Replies {
+		if topic.Replies[i].ID != "" {
+			continue
+		}
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		topic.Replies[i].ID = id
+	}
+	return nil
+}
+
+// newID returns a random 12-character hex ID for a topic or reply
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,74 @@
+package community
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// preamblePatterns match chatty lead-ins models put before the actual post
+	preamblePatterns = []*regexp.Regexp{
+		// "Sure!" on its own; "Sure, I think..." is left alone
+		regexp.MustCompile(`(?i)^(?:sure|certainly|absolutely|of course|okay|ok|great)[!.]+\s*`),
+		// "Sure, here's a topic for our community:"; not "Here's the thing:"
+		regexp.MustCompile(`(?i)^(?:(?:sure|certainly|absolutely|of course|okay|ok)[!,.]*\s*)?here(?:'s| is| are)\s+(?:[\w-]+\s+){0,5}?(?:topic|reply|response|post|comment|question|thought|take|idea)s?\b[^:\n]{0,60}:\s*`),
+		regexp.MustCompile(`(?i)^(?:\*\*)?(?:topic|title|reply|response|post)(?:\*\*)?\s*:\s*(?:\*\*)?\s*`),
+	}
+
+	horizontalSpace = regexp.MustCompile(`[ \t]+`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+// quotePairs are the wrappers stripped from around a whole generation
+var quotePairs = [][2]string{{`"`, `"`}, {"“", "”"}, {"'", "'"}, {"‘", "’"}, {"`", "`"}}
+
+// SanitizeGeneration cleans up model output before it is stored: it drops
+// preambles such as "Sure! Here's a topic:", strips quotes wrapped around
+// the whole text, and collapses runs of spaces and blank lines. Line breaks
+// and leading indentation are kept so nested Markdown lists survive, and
+// fenced code blocks only lose trailing spaces.
+func SanitizeGeneration(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	for {
+		before := s
+		s = strings.TrimSpace(s)
+		for _, p := range preamblePatterns {
+			s = p.ReplaceAllString(s, "")
+		}
+		s = stripWrappingQuotes(s)
+		if s == before {
+			break
+		}
+	}
+
+	lines := strings.Split(s, "\n")
+	inFence := false
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence {
+			body := strings.TrimLeft(line, " \t")
+			line = line[:len(line)-len(body)] + horizontalSpace.ReplaceAllString(body, " ")
+		}
+		lines[i] = line
+	}
+	return blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
+
+// stripWrappingQuotes removes one pair of matching quotes enclosing s, as
+// long as the quote character doesn't also appear inside
+func stripWrappingQuotes(s string) string {
+	for _, q := range quotePairs {
+		if len(s) < len(q[0])+len(q[1]) || !strings.HasPrefix(s, q[0]) || !strings.HasSuffix(s, q[1]) {
+			continue
+		}
+		inner := s[len(q[0]) : len(s)-len(q[1])]
+		if strings.Contains(inner, q[0]) || strings.Contains(inner, q[1]) {
+			continue
+		}
+		return strings.TrimSpace(inner)
+	}
+	return s
+}
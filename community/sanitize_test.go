@@ -0,0 +1,64 @@
+package community
+
+import "testing"
+
+func TestSanitizeGeneration(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			name: "preamble and wrapping quotes",
+			in:   "Sure! Here's a topic for our community:\n\n\"Is sous vide overrated?\"",
+			want: "Is sous vide overrated?",
+		},
+		{
+			name: "label and padding",
+			in:   "  \n**Reply:**   I    think the   crust matters most.  \n\n",
+			want: "I think the crust matters most.",
+		},
+		{
+			name: "curly quotes",
+			in:   "“Butter is a seasoning, not a fat.”",
+			want: "Butter is a seasoning, not a fat.",
+		},
+		{
+			name: "quotes inside are kept",
+			in:   `"Salt" early, "salt" often`,
+			want: `"Salt" early, "salt" often`,
+		},
+		{
+			name: "conversational opener is kept",
+			in:   "Sure, I think brining is worth it.",
+			want: "Sure, I think brining is worth it.",
+		},
+		{
+			name: "blank lines and CRLF",
+			in:   "First paragraph.\r\n\r\n\r\n\r\nSecond   paragraph.\r\n",
+			want: "First paragraph.\n\nSecond paragraph.",
+		},
+		{
+			name: "nested list keeps its indentation",
+			in:   "Here is my reply:\n- Knives\n  - Chef's   knife   \n  - Paring knife\n- Pans",
+			want: "- Knives\n  - Chef's knife\n  - Paring knife\n- Pans",
+		},
+		{
+			name: "code block keeps its spacing",
+			in:   "Try this:\n\n```\nfor i := 0;  i < 3;  i++ {\n    stir()   \n}\n```",
+			want: "Try this:\n\n```\nfor i := 0;  i < 3;  i++ {\n    stir()\n}\n```",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeGeneration(tt.in); got != tt.want {
+				t.Errorf("SanitizeGeneration(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopicFilenameSanitizesTitle(t *testing.T) {
+	topic := Topic{Title: `Sure! Here's a topic: "Why Rest Your Steak?"`, Timestamp: "2024-05-01T09:30:00Z"}
+	if got, want := topicFilename(topic), "20240501-093000_why_rest_your_steak.json"; got != want {
+		t.Errorf("topicFilename = %q, want %q", got, want)
+	}
+}
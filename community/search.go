@@ -0,0 +1,77 @@
+package community
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrEmptyQuery is returned by SearchTopics when the query has no terms
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// Relevance weights for a term found in each part of a topic
+const (
+	titleMatchWeight = 10
+	bodyMatchWeight  = 3
+	replyMatchWeight = 1
+)
+
+// SearchTopics returns the topics in dir containing every whitespace-separated
+// term of query, ignoring case, in their title, body, or replies. Results are
+// ranked by relevance, with title matches weighing most; ties keep the newest
+// topic first.
+func SearchTopics(dir, query string) ([]Topic, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		topic Topic
+		score int
+	}
+	var matches []match
+	for _, topic := range topics {
+		if score := relevance(topic, terms); score > 0 {
+			matches = append(matches, match{topic, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	results := make([]Topic, len(matches))
+	for i, m := range matches {
+		results[i] = m.topic
+	}
+	return results, nil
+}
+
+// relevance scores topic against terms, or returns 0 if any term is missing
+func relevance(topic Topic, terms []string) int {
+	title := strings.ToLower(topic.Title)
+	body := strings.ToLower(topic.Body)
+	replies := make([]string, len(topic.Replies))
+	for i, reply := range topic.Replies {
+		replies[i] = strings.ToLower(reply.Content)
+	}
+
+	score := 0
+	for _, term := range terms {
+		termScore := titleMatchWeight*strings.Count(title, term) + bodyMatchWeight*strings.Count(body, term)
+		for _, content := range replies {
+			termScore += replyMatchWeight * strings.Count(content, term)
+		}
+		if termScore == 0 {
+			return 0
+		}
+		score += termScore
+	}
+	return score
+}
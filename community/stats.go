@@ -0,0 +1,33 @@
+package community
+
+// AgentActivity counts one author's contributions to the community
+type AgentActivity struct {
+	Topics  int `json:"topics"`
+	Replies int `json:"replies"`
+	Upvotes int `json:"upvotes"` // upvotes received on their topics
+}
+
+// AgentStats tallies, per author as stored on topics and replies, how many
+// topics they created, how many replies they wrote, and how many upvotes
+// their topics received.
+func AgentStats(dir string) (map[string]AgentActivity, error) {
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]AgentActivity)
+	for _, topic := range topics {
+		activity := stats[topic.Author]
+		activity.Topics++
+		activity.Upvotes += topic.Upvotes
+		stats[topic.Author] = activity
+
+		for _, reply := range topic.Replies {
+			activity := stats[reply.Author]
+			activity.Replies++
+			stats[reply.Author] = activity
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,164 @@
+package community
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store keeps the topics of one community directory in memory so repeated
+// listings don't re-read every file. Writes made through this package
+// invalidate it, and so does a change to the directory's modification time,
+// which catches topics created or removed by another process. Call Refresh
+// after editing files in subdirectories by hand.
+type Store struct {
+	dir string
+
+	mu         sync.RWMutex
+	topics     []Topic
+	skipped    []CorruptFile
+	modTime    time.Time
+	generation int // bumped by Invalidate
+	valid      bool
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]*Store)
+)
+
+// NewStore returns the store for dir. Stores are shared, so every caller
+// using the same directory sees the same cache.
+func NewStore(dir string) (*Store, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving community directory: %w", err)
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, ok := stores[absDir]; ok {
+		return s, nil
+	}
+	s := &Store{dir: absDir}
+	stores[absDir] = s
+	return s, nil
+}
+
+// Topics returns every topic, newest first, reloading them if the cache is
+// stale. The result is a copy the caller may modify.
+func (s *Store) Topics() ([]Topic, error) {
+	modTime := dirModTime(s.dir)
+
+	s.mu.RLock()
+	if s.valid && modTime.Equal(s.modTime) {
+		defer s.mu.RUnlock()
+		return cloneTopics(s.topics), nil
+	}
+	s.mu.RUnlock()
+
+	topics, err := s.load(modTime)
+	if err != nil {
+		return nil, err
+	}
+	return cloneTopics(topics), nil
+}
+
+// Skipped returns the files left out of the last load because they couldn't
+// be read or decoded
+func (s *Store) Skipped() []CorruptFile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]CorruptFile(nil), s.skipped...)
+}
+
+// fresh reports whether the cached topics are current
+func (s *Store) fresh() bool {
+	modTime := dirModTime(s.dir)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.valid && modTime.Equal(s.modTime)
+}
+
+// Refresh reloads every topic from disk
+func (s *Store) Refresh() error {
+	_, err := s.load(dirModTime(s.dir))
+	return err
+}
+
+// Invalidate drops the cached topics so the next read reloads them
+func (s *Store) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = false
+	s.generation++
+}
+
+// load reads the directory and caches the result, unless the store was
+// invalidated while reading, in which case the next read loads again
+func (s *Store) load(modTime time.Time) ([]Topic, error) {
+	s.mu.RLock()
+	generation := s.generation
+	s.mu.RUnlock()
+
+	topics, skipped, err := readTopicsDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation == generation {
+		s.topics, s.skipped, s.modTime, s.valid = topics, skipped, modTime, true
+	}
+	return topics, nil
+}
+
+// invalidateStore invalidates the store for absDir, if one exists
+func invalidateStore(absDir string) {
+	storesMu.Lock()
+	s := stores[absDir]
+	storesMu.Unlock()
+	if s != nil {
+		s.Invalidate()
+	}
+}
+
+// dirModTime returns the directory's modification time, or the zero time if
+// it can't be read
+func dirModTime(dir string) time.Time {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// cloneTopics copies topics deeply enough that callers can change anything
+// in them, replies and debug details included, without touching the cache
+func cloneTopics(topics []Topic) []Topic {
+	clone := make([]Topic, len(topics))
+	for i, t := range topics {
+		t.Tags = append([]string(nil), t.Tags...)
+		t.Replies = append([]Reply(nil), t.Replies...)
+		for j := range t.Replies {
+			t.Replies[j].Mentions = append([]string(nil), t.Replies[j].Mentions...)
+			t.Replies[j].Debug = clonePointer(t.Replies[j].Debug)
+		}
+		t.Debug = clonePointer(t.Debug)
+		t.ContextSummary = clonePointer(t.ContextSummary)
+		clone[i] = t
+	}
+	return clone
+}
+
+// clonePointer returns a pointer to a copy of *p, or nil for nil
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
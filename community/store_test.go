@@ -0,0 +1,42 @@
+package community
+
+import "testing"
+
+func TestCloneTopicsSharesNothingWithTheCache(t *testing.T) {
+	cached := []Topic{{
+		Title:          "Knives",
+		Tags:           []string{"tools"},
+		Debug:          &Debug{QualityScore: 7},
+		ContextSummary: &ContextSummary{ReplyCount: 3, Text: "earlier"},
+		Replies: []Reply{{
+			Author:   "bob",
+			Content:  "@carol thoughts?",
+			Mentions: []string{"carol"},
+			Debug:    &Debug{QualityScore: 8},
+		}},
+	}}
+
+	clone := cloneTopics(cached)
+	clone[0].Tags[0] = "changed"
+	clone[0].Debug.QualityScore = 1
+	clone[0].ContextSummary.Text = "changed"
+	clone[0].Replies[0].Mentions[0] = "changed"
+	clone[0].Replies[0].Debug.QualityScore = 1
+
+	topic := cached[0]
+	if topic.Tags[0] != "tools" {
+		t.Error("clone shares tags")
+	}
+	if topic.Debug.QualityScore != 7 {
+		t.Error("clone shares the topic's debug details")
+	}
+	if topic.ContextSummary.Text != "earlier" {
+		t.Error("clone shares the context summary")
+	}
+	if topic.Replies[0].Mentions[0] != "carol" {
+		t.Error("clone shares reply mentions")
+	}
+	if topic.Replies[0].Debug.QualityScore != 8 {
+		t.Error("clone shares the reply's debug details")
+	}
+}
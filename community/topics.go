@@ -1,108 +1,472 @@
 package community
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 // Topic represents a discussion topic
 type Topic struct {
-	Title     string   `json:"title"`
-	Body      string   `json:"body"`
-	Author    string   `json:"author"`
-	Upvotes   int      `json:"upvotes"`
-	Downvotes int      `json:"downvotes"`
-	Timestamp string   `json:"timestamp"`
-	Tags      []string `json:"tags"`
-	Replies   []Reply  `json:"replies"`
-	Filename  string   `json:"-"`
+	ID             string          `json:"id,omitempty"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	Author         string          `json:"author"`
+	Upvotes        int             `json:"upvotes"`
+	Downvotes      int             `json:"downvotes"`
+	Timestamp      string          `json:"timestamp"`
+	EditedAt       string          `json:"edited_at,omitempty"` // when Title, Body, or Tags last changed; see UpdateTopic
+	Pinned         bool            `json:"pinned,omitempty"`
+	Archived       bool            `json:"archived,omitempty"` // hidden from listings and closed to replies; see ArchiveTopic
+	Board          string          `json:"board,omitempty"`    // section of the community; empty means DefaultBoard
+	Lang           string          `json:"lang,omitempty"`     // language code of Title and Body; empty is English
+	Tags           []string        `json:"tags"`
+	Image          string          `json:"image,omitempty"` // file name under the data directory's images/
+	Replies        []Reply         `json:"replies"`
+	Debug          *Debug          `json:"debug,omitempty"`
+	ContextSummary *ContextSummary `json:"context_summary,omitempty"`
+	Filename       string          `json:"-"`
 }
 
 // Reply represents a reply to a topic
 type Reply struct {
-	Author    string `json:"author"`
-	Content   string `json:"content"`
-	Timestamp string `json:"timestamp"`
+	ID        string   `json:"id,omitempty"`
+	ParentID  string   `json:"parent_id,omitempty"`
+	Author    string   `json:"author"`
+	Content   string   `json:"content"`
+	Timestamp string   `json:"timestamp"`
+	EditedAt  string   `json:"edited_at,omitempty"` // when Content last changed; see UpdateTopic
+	Upvotes   int      `json:"upvotes,omitempty"`
+	Downvotes int      `json:"downvotes,omitempty"`
+	Mentions  []string `json:"mentions,omitempty"` // IDs of agents @mentioned in Content
+	Lang      string   `json:"lang,omitempty"`     // language code of Content; empty is English
+	Debug     *Debug   `json:"debug,omitempty"`
+}
+
+// ContextSummary is a model-written digest of a topic's first ReplyCount replies
+type ContextSummary struct {
+	ReplyCount int    `json:"reply_count"`
+	Text       string `json:"text"`
+}
+
+// Debug holds generation diagnostics stored alongside generated content
+type Debug struct {
+	QualityScore    int `json:"quality_score,omitempty"`
+	QualityAttempts int `json:"quality_attempts,omitempty"`
 }
 
 // Config represents community configuration for seeding
 type Config struct {
 	Domain     string      `json:"domain"`
 	Tags       []string    `json:"tags"`
+	Boards     []string    `json:"boards,omitempty"` // sections agents post new topics to; none means DefaultBoard only
 	SeedTopics []SeedTopic `json:"seed_topics"`
 }
 
 // SeedTopic represents a seed topic for initialization
 type SeedTopic struct {
-	Title  string   `json:"title"`
-	Body   string   `json:"body"`
-	Author string   `json:"author"`
-	Tags   []string `json:"tags"`
+	Title   string   `json:"title"`
+	Body    string   `json:"body"`
+	Author  string   `json:"author"`
+	Board   string   `json:"board,omitempty"`
+	Tags    []string `json:"tags"`
+	Image   string   `json:"image,omitempty"`
+	Replies []Reply  `json:"replies,omitempty"`
 }
 
-// LoadRecentTopics loads the most recent topics from the community directory
+// ErrInvalidTopicPath is returned when a topic path points outside the
+// community directory.
+var ErrInvalidTopicPath = errors.New("invalid topic path")
+
+// LoadRecentTopics loads the most recent topics that aren't archived from the
+// community directory.
+// Unless the directory's Store already holds them, only the newest files are
+// decoded, found by the creation time in their names; if any file predates
+// that naming scheme, every topic is loaded instead.
 func LoadRecentTopics(dir string, limit int) ([]Topic, error) {
-	topics, err := LoadTopics(dir)
+	if limit <= 0 {
+		return LoadTopics(dir)
+	}
+
+	store, err := NewStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	if store.fresh() {
+		topics, err := store.Topics()
+		if err != nil {
+			return nil, err
+		}
+		return paginate(activeTopics(topics), 0, limit), nil
+	}
+
+	paths, err := topicFiles(store.dir)
 	if err != nil {
 		return nil, err
 	}
+
+	stamps := make(map[string]string, len(paths))
+	for _, path := range paths {
+		stamp, ok := filenameTimestamp(path)
+		if !ok {
+			return LoadTopics(dir)
+		}
+		stamps[path] = stamp
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return stamps[paths[i]] > stamps[paths[j]]
+	})
+
+	// Filenames only have second precision, so keep reading past the limit
+	// while files share the last creation second, then order by timestamp
+	var topics []Topic
+	var lastStamp string
+	for _, path := range paths {
+		if len(topics) >= limit && stamps[path] != lastStamp {
+			break
+		}
+		topic, err := readTopicFile(store.dir, path)
+		if err != nil || topic.Archived {
+			continue // failures are logged by readTopicFile
+		}
+		topics = append(topics, topic)
+		lastStamp = stamps[path]
+	}
+
+	sortNewestFirst(topics)
+	return paginate(topics, 0, limit), nil
+}
+
+// filenameTimestamp returns the creation time prefix ("20060102-150405") of
+// a topic file name, if it has one
+func filenameTimestamp(path string) (string, bool) {
+	const layout = "20060102-150405"
+	name := filepath.Base(path)
+	if len(name) <= len(layout) || name[len(layout)] != '_' {
+		return "", false
+	}
+	if _, err := time.Parse(layout, name[:len(layout)]); err != nil {
+		return "", false
+	}
+	return name[:len(layout)], true
+}
+
+// Sort orders accepted by LoadTopicsPaged
+const (
+	SortNew    = "new"    // newest topics first, as LoadTopics returns them
+	SortActive = "active" // most recent reply (or topic, if unanswered) first
+)
+
+// LoadTopicsPaged returns up to limit topics starting at offset in the given
+// sort order, along with the total number of topics. An unknown order falls
+// back to SortNew, and an offset past the end yields an empty page.
+func LoadTopicsPaged(dir, order string, offset, limit int) ([]Topic, int, error) {
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	if order == SortActive {
+		sort.SliceStable(topics, func(i, j int) bool {
+			return ActivityTime(topics[i]).After(ActivityTime(topics[j]))
+		})
+	}
+	return paginate(topics, offset, limit), len(topics), nil
+}
+
+// paginate returns the window of topics starting at offset; a limit of 0 or
+// less means no limit.
+func paginate(topics []Topic, offset, limit int) []Topic {
+	offset = max(0, offset)
+	if offset >= len(topics) {
+		return []Topic{}
+	}
+	topics = topics[offset:]
 	if limit > 0 && len(topics) > limit {
 		topics = topics[:limit]
 	}
-	return topics, nil
+	return topics
+}
+
+// LoadTopicsByTag returns the topics carrying tag, compared case-insensitively,
+// newest first. An unknown tag yields an empty list.
+func LoadTopicsByTag(dir, tag string) ([]Topic, error) {
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tag = strings.TrimSpace(tag)
+	matches := []Topic{}
+	for _, topic := range topics {
+		for _, t := range topic.Tags {
+			if strings.EqualFold(strings.TrimSpace(t), tag) {
+				matches = append(matches, topic)
+				break
+			}
+		}
+	}
+	return matches, nil
 }
 
+// LoadTopicsByBoard returns the topics in board, newest first, matching
+// case-insensitively. Topics without a board belong to DefaultBoard.
+func LoadTopicsByBoard(dir, board string) ([]Topic, error) {
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	board = BoardName(board)
+	matches := []Topic{}
+	for _, topic := range topics {
+		if BoardName(topic.Board) == board {
+			matches = append(matches, topic)
+		}
+	}
+	return matches, nil
+}
+
+// LoadTopicsByAuthor returns the topics author started and, separately, the
+// other topics they replied to, both newest first. Authors are compared as
+// stored, normally by agent ID.
+func LoadTopicsByAuthor(dir, author string) (started, repliedTo []Topic, err error) {
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	started, repliedTo = []Topic{}, []Topic{}
+	for _, topic := range topics {
+		if topic.Author == author {
+			started = append(started, topic)
+			continue
+		}
+		for _, reply := range topic.Replies {
+			if reply.Author == author {
+				repliedTo = append(repliedTo, topic)
+				break
+			}
+		}
+	}
+	return started, repliedTo, nil
+}
+
+// LoadTopics returns every topic in the community directory that isn't
+// archived, newest first, served from the directory's Store. Files that
+// can't be read or decoded are skipped and logged once; LoadTopicsChecked
+// lists them.
 func LoadTopics(dir string) ([]Topic, error) {
+	topics, err := LoadAllTopics(dir)
+	if err != nil {
+		return nil, err
+	}
+	return activeTopics(topics), nil
+}
+
+// LoadAllTopics is LoadTopics including archived topics
+func LoadAllTopics(dir string) ([]Topic, error) {
+	store, err := NewStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	return store.Topics()
+}
+
+// LoadTopicsChecked returns every topic, archived ones included, along with
+// the topic files that were skipped because they couldn't be read or decoded
+func LoadTopicsChecked(dir string) ([]Topic, []CorruptFile, error) {
+	store, err := NewStore(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	topics, err := store.Topics()
+	if err != nil {
+		return nil, nil, err
+	}
+	return topics, store.Skipped(), nil
+}
+
+// CorruptFile is a topic file that was skipped while loading the community
+type CorruptFile struct {
+	Path string // relative to the community directory
+	Err  error
+}
+
+// readTopicsDir decodes every topic file under absDir, newest first, along
+// with the files it had to skip
+func readTopicsDir(absDir string) ([]Topic, []CorruptFile, error) {
+	paths, err := topicFiles(absDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	topics := make([]Topic, 0, len(paths))
+	var skipped []CorruptFile
+	for _, path := range paths {
+		topic, err := readTopicFile(absDir, path)
+		if err != nil {
+			rel, relErr := filepath.Rel(absDir, path)
+			if relErr != nil {
+				rel = path
+			}
+			skipped = append(skipped, CorruptFile{Path: rel, Err: err})
+			continue
+		}
+		topics = append(topics, topic)
+	}
+
+	sortNewestFirst(topics)
+	return topics, skipped, nil
+}
+
+// CountTopicFiles counts the topic files under dir without reading them,
+// nested directories and archived topics included. Unlike the loaders, it
+// fails when dir doesn't exist.
+func CountTopicFiles(dir string) (int, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
-		return nil, fmt.Errorf("resolving community directory: %w", err)
+		return 0, fmt.Errorf("resolving community directory: %w", err)
 	}
+	if _, err := os.Stat(absDir); err != nil {
+		return 0, fmt.Errorf("reading community directory: %w", err)
+	}
+	paths, err := topicFiles(absDir)
+	return len(paths), err
+}
 
-	var topics []Topic
+// topicFiles lists the topic files under absDir
+func topicFiles(absDir string) ([]string, error) {
+	var paths []string
 	if err := filepath.WalkDir(absDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if d.IsDir() {
-			return nil
-		}
-		if !strings.HasSuffix(strings.ToLower(d.Name()), ".json") {
-			return nil
-		}
-		topic, err := loadTopic(path)
-		if err != nil {
-			return nil // skip corrupted files silently
-		}
-		if rel, relErr := filepath.Rel(absDir, path); relErr == nil {
-			topic.Filename = rel
+		if !d.IsDir() && strings.HasSuffix(strings.ToLower(d.Name()), ".json") {
+			paths = append(paths, path)
 		}
-		topics = append(topics, topic)
 		return nil
 	}); err != nil {
 		if os.IsNotExist(err) {
-			return []Topic{}, nil
+			return nil, nil
 		}
 		return nil, fmt.Errorf("walking community directory: %w", err)
 	}
+	return paths, nil
+}
 
-	sort.Slice(topics, func(i, j int) bool {
-		return topics[i].Timestamp > topics[j].Timestamp
-	})
+// readTopicFile loads the topic at path, naming it relative to absDir. A
+// failure is logged, once per file and error, since callers listing the
+// community skip the file and carry on.
+func readTopicFile(absDir, path string) (Topic, error) {
+	topic, err := loadTopic(path)
+	noteCorrupt(path, err)
+	if err != nil {
+		return Topic{}, err
+	}
+	if rel, relErr := filepath.Rel(absDir, path); relErr == nil {
+		topic.Filename = rel
+	}
+	ensureTopicID(&topic, topic.Filename)
+	return topic, nil
+}
+
+var (
+	corruptMu     sync.Mutex
+	corruptLogged = make(map[string]string) // topic file path -> error last logged for it
+)
 
-	return topics, nil
+// noteCorrupt logs that the topic file at path failed to load, unless the
+// same error was already logged for it, so a broken file is reported without
+// flooding the log on every listing. A nil err clears the file's record.
+func noteCorrupt(path string, err error) {
+	corruptMu.Lock()
+	defer corruptMu.Unlock()
+	if err == nil {
+		delete(corruptLogged, path)
+		return
+	}
+	if corruptLogged[path] == err.Error() {
+		return
+	}
+	corruptLogged[path] = err.Error()
+	log.Printf("community: skipping unreadable topic file %s: %v", path, err)
+}
+
+// sortNewestFirst orders topics by their parsed creation time, so mixed
+// timestamp formats and time zones compare correctly. Topics whose timestamp
+// can't be parsed are logged and sorted last.
+func sortNewestFirst(topics []Topic) {
+	created := make(map[string]time.Time, len(topics))
+	for _, t := range topics {
+		parsed, err := ParseTimestamp(t.Timestamp)
+		if err != nil {
+			log.Printf("community: %s: %v, sorting it last (see -fix-timestamps)", filepath.ToSlash(t.Filename), err)
+		}
+		created[t.Filename] = parsed
+	}
+	sort.SliceStable(topics, func(i, j int) bool {
+		return created[topics[i].Filename].After(created[topics[j].Filename])
+	})
 }
 
-// SaveTopic saves a topic to the community directory
+// SaveTopic saves a topic to the community directory, replacing the stored
+// copy wholesale. To change part of an existing topic without losing
+// concurrent updates, use UpdateTopic.
 func SaveTopic(topic Topic, dir string) error {
+	if topic.Filename != "" {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("resolving community directory: %w", err)
+		}
+		path := topic.Filename
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(absDir, path)
+		}
+		unlock := lockTopic(path)
+		defer unlock()
+	}
+	_, err := saveTopic(topic, dir)
+	return err
+}
+
+// CreateTopic saves topic as a new file in dir and returns it as stored,
+// with Filename set relative to dir.
+func CreateTopic(dir string, topic Topic) (Topic, error) {
+	topic.Filename = ""
+	return saveTopic(topic, dir)
+}
+
+// saveTopic writes topic to its file, or to a newly named one when Filename
+// is empty, and returns the topic as written. Callers updating an existing
+// file must hold its lock.
+func saveTopic(topic Topic, dir string) (_ Topic, err error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
-		return fmt.Errorf("resolving community directory: %w", err)
+		return Topic{}, fmt.Errorf("resolving community directory: %w", err)
+	}
+
+	isNew := topic.Filename == ""
+
+	// IDs come first, so a failure leaves nothing behind on disk
+	if topic.ID == "" {
+		if topic.ID, err = newID(); err != nil {
+			return Topic{}, err
+		}
+	}
+	if err := assignReplyIDs(&topic); err != nil {
+		return Topic{}, err
 	}
 
 	var path string
@@ -114,35 +478,41 @@ func SaveTopic(topic Topic, dir string) error {
 	} else {
 		// Create directory if it doesn't exist
 		if err := os.MkdirAll(absDir, 0755); err != nil {
-			return fmt.Errorf("creating community directory: %w", err)
+			return Topic{}, fmt.Errorf("creating community directory: %w", err)
 		}
 
-		// Generate filename from title (simplified)
-		filename := strings.ReplaceAll(strings.ToLower(topic.Title), " ", "_")
-		filename = strings.ReplaceAll(filename, "'", "")
-		filename = fmt.Sprintf("%s.json", filename[:min(50, len(filename))])
-
-		path = filepath.Join(absDir, filename)
+		path, err = reserveTopicFile(absDir, topicFilename(topic))
+		if err != nil {
+			return Topic{}, err
+		}
+		// An empty placeholder left behind would read as a corrupt topic
+		defer func() {
+			if err != nil {
+				os.Remove(path)
+			}
+		}()
 	}
 
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return fmt.Errorf("creating topic directory: %w", err)
+		return Topic{}, fmt.Errorf("creating topic directory: %w", err)
 	}
 
 	data, err := json.MarshalIndent(topic, "", "  ")
 	if err != nil {
-		return fmt.Errorf("marshaling topic: %w", err)
+		return Topic{}, fmt.Errorf("marshaling topic: %w", err)
 	}
 
 	// Atomic write
 	tempFile := path + ".tmp"
 	if err := os.WriteFile(tempFile, data, 0644); err != nil {
-		return fmt.Errorf("writing temp file: %w", err)
+		return Topic{}, fmt.Errorf("writing temp file: %w", err)
 	}
 
 	if err := os.Rename(tempFile, path); err != nil {
-		return fmt.Errorf("renaming temp file: %w", err)
+		os.Remove(tempFile)
+		return Topic{}, fmt.Errorf("renaming temp file: %w", err)
 	}
+	invalidateStore(absDir)
 
 	if rel, relErr := filepath.Rel(absDir, path); relErr == nil {
 		topic.Filename = rel
@@ -150,10 +520,88 @@ func SaveTopic(topic Topic, dir string) error {
 		topic.Filename = path
 	}
 
-	return nil
+	if isNew {
+		publish(Event{
+			Type:      EventTopicCreated,
+			Title:     topic.Title,
+			Path:      filepath.ToSlash(topic.Filename),
+			Author:    topic.Author,
+			Timestamp: topic.Timestamp,
+		})
+	}
+
+	return topic, nil
+}
+
+// topicFilename names a new topic file: a sortable creation time followed by
+// a slug of the title, e.g. "20240501-093000_why_go.json". The title is
+// sanitized first, so a stray "Sure! Here's a topic:" doesn't end up in the
+// name.
+func topicFilename(topic Topic) string {
+	created, err := ParseTimestamp(topic.Timestamp)
+	if err != nil {
+		created = time.Now()
+	}
+	return fmt.Sprintf("%s_%s.json", created.UTC().Format("20060102-150405"), slugify(SanitizeGeneration(topic.Title), 50))
 }
 
-// AddReplyToTopic adds a reply to an existing topic
+// slugify lowercases title and joins its letters and digits with single
+// underscores, keeping at most limit characters.
+func slugify(title string, limit int) string {
+	var b strings.Builder
+	pending := false
+	for _, r := range strings.ToLower(title) {
+		if r == '\'' || r == '’' {
+			continue
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			pending = b.Len() > 0
+			continue
+		}
+		if pending {
+			b.WriteByte('_')
+			pending = false
+		}
+		b.WriteRune(r)
+	}
+
+	slug := []rune(b.String())
+	if len(slug) > limit {
+		slug = slug[:limit]
+	}
+	if s := strings.TrimRight(string(slug), "_"); s != "" {
+		return s
+	}
+	return "topic"
+}
+
+// reserveTopicFile creates an empty file for a new topic under absDir,
+// appending _2, _3, ... to the name until it does not clash with an existing
+// topic, and returns its path.
+func reserveTopicFile(absDir, filename string) (string, error) {
+	base := strings.TrimSuffix(filename, ".json")
+	for n := 1; ; n++ {
+		name := filename
+		if n > 1 {
+			name = fmt.Sprintf("%s_%d.json", base, n)
+		}
+		path := filepath.Join(absDir, name)
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("creating topic file: %w", err)
+		}
+		f.Close()
+		return path, nil
+	}
+}
+
+// AddReplyToTopic adds a reply to the first topic whose title matches.
+//
+// Deprecated: titles can collide; use AddReplyByPath.
 func AddReplyToTopic(topicTitle string, reply Reply, dir string) error {
 	topics, err := LoadTopics(dir)
 	if err != nil {
@@ -162,25 +610,185 @@ func AddReplyToTopic(topicTitle string, reply Reply, dir string) error {
 
 	for _, topic := range topics {
 		if topic.Title == topicTitle {
-			topic.Replies = append(topic.Replies, reply)
-			return SaveTopic(topic, dir)
+			return AddReplyByPath(dir, topic.Filename, reply)
 		}
 	}
 
 	return fmt.Errorf("topic not found: %s", topicTitle)
 }
 
+// AddReplyByPath appends reply to the topic stored at relPath
+func AddReplyByPath(dir, relPath string, reply Reply) error {
+	return AddRepliesByPath(dir, relPath, []Reply{reply})
+}
+
+// AddReplyByID appends reply to the topic with the given ID
+func AddReplyByID(dir, id string, reply Reply) error {
+	topic, err := LoadTopicByID(dir, id)
+	if err != nil {
+		return err
+	}
+	return AddReplyByPath(dir, topic.Filename, reply)
+}
+
+// AddRepliesByPath appends replies, in order, to the topic stored at relPath
+// using a single load and save.
+func AddRepliesByPath(dir, relPath string, replies []Reply) error {
+	topic, err := modifyTopic(dir, relPath, func(t *Topic) error {
+		if t.Archived {
+			return fmt.Errorf("%w: %s", ErrTopicArchived, relPath)
+		}
+		t.Replies = append(t.Replies, replies...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rel := filepath.ToSlash(filepath.Clean(relPath))
+	for _, reply := range replies {
+		publish(Event{
+			Type:      EventReplyAdded,
+			Title:     topic.Title,
+			Path:      rel,
+			Author:    reply.Author,
+			Content:   reply.Content,
+			Timestamp: reply.Timestamp,
+		})
+	}
+	return nil
+}
+
+// UpvoteTopic adds an upvote to the topic stored at relPath
+func UpvoteTopic(dir, relPath string) error {
+	_, err := modifyTopic(dir, relPath, func(t *Topic) error {
+		t.Upvotes++
+		return nil
+	})
+	return err
+}
+
+// DownvoteTopic adds a downvote to the topic stored at relPath
+func DownvoteTopic(dir, relPath string) error {
+	_, err := modifyTopic(dir, relPath, func(t *Topic) error {
+		t.Downvotes++
+		return nil
+	})
+	return err
+}
+
+// DeleteTopic removes the topic file at relPath. Deleting a topic that does
+// not exist fails with an error wrapping fs.ErrNotExist.
+func DeleteTopic(dir, relPath string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolving community directory: %w", err)
+	}
+
+	path, err := resolveTopicPath(absDir, relPath)
+	if err != nil {
+		return err
+	}
+	unlock := lockTopic(path)
+	defer unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("reading topic file info: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s is not a topic file", ErrInvalidTopicPath, relPath)
+	}
+
+	// Corrupt files can still be deleted; the title is only for the event
+	topic, _ := loadTopic(path)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("deleting topic file: %w", err)
+	}
+	invalidateStore(absDir)
+
+	publish(Event{
+		Type:      EventTopicDeleted,
+		Title:     topic.Title,
+		Path:      filepath.ToSlash(filepath.Clean(relPath)),
+		Author:    topic.Author,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	return nil
+}
+
+// UpdateTopic applies fn to the topic stored at relPath and saves it in
+// place. The file keeps its name even if fn changes the title, so links to
+// the topic stay valid. If fn changes the title, body, or tags, or a reply's
+// content, EditedAt is set on the topic or reply; other changes, such as
+// cached summaries or migrated authors, aren't edits.
+func UpdateTopic(dir, relPath string, fn func(*Topic)) error {
+	_, err := modifyTopic(dir, relPath, func(t *Topic) error {
+		title, body, tags := t.Title, t.Body, slices.Clone(t.Tags)
+		contents := make([]string, len(t.Replies))
+		for i, reply := range t.Replies {
+			contents[i] = reply.Content
+		}
+
+		fn(t)
+
+		now := time.Now().Format(time.RFC3339)
+		if t.Title != title || t.Body != body || !slices.Equal(t.Tags, tags) {
+			t.EditedAt = now
+		}
+		// Replies are compared in place; fn may append but not reorder them
+		for i := range t.Replies[:min(len(t.Replies), len(contents))] {
+			if t.Replies[i].Content != contents[i] {
+				t.Replies[i].EditedAt = now
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// modifyTopic loads the topic at relPath, applies fn, and saves the result
+// while holding the topic's lock, so concurrent changes are not lost. If fn
+// fails nothing is saved. It returns the saved topic.
+func modifyTopic(dir, relPath string, fn func(*Topic) error) (Topic, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return Topic{}, fmt.Errorf("resolving community directory: %w", err)
+	}
+
+	path, err := resolveTopicPath(absDir, relPath)
+	if err != nil {
+		return Topic{}, err
+	}
+
+	unlock := lockTopic(path)
+	defer unlock()
+
+	topic, err := loadTopic(path)
+	if err != nil {
+		return Topic{}, err
+	}
+	if rel, relErr := filepath.Rel(absDir, path); relErr == nil {
+		ensureTopicID(&topic, rel)
+	}
+	if err := fn(&topic); err != nil {
+		return Topic{}, err
+	}
+	topic.Filename = path // always write back to the same file
+
+	return saveTopic(topic, dir)
+}
+
 func LoadTopicByRelativePath(dir, relPath string) (Topic, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return Topic{}, fmt.Errorf("resolving community directory: %w", err)
 	}
 
-	clean := filepath.Clean(relPath)
-	if strings.HasPrefix(clean, "..") {
-		return Topic{}, fmt.Errorf("invalid topic path: %s", relPath)
+	path, err := resolveTopicPath(absDir, relPath)
+	if err != nil {
+		return Topic{}, err
 	}
-	path := filepath.Join(absDir, clean)
 	topic, err := loadTopic(path)
 	if err != nil {
 		return Topic{}, err
@@ -188,17 +796,97 @@ func LoadTopicByRelativePath(dir, relPath string) (Topic, error) {
 	if rel, relErr := filepath.Rel(absDir, path); relErr == nil {
 		topic.Filename = rel
 	}
+	ensureTopicID(&topic, topic.Filename)
 	return topic, nil
 }
 
-// InitializeIfEmpty initializes the community with seed topics if empty
-func InitializeIfEmpty(configPath string) error {
+// TopicModTime returns when the topic file at relPath was last written
+func TopicModTime(dir, relPath string) (time.Time, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("resolving community directory: %w", err)
+	}
+	path, err := resolveTopicPath(absDir, relPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading topic file info: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
+// LoadTopicByID returns the topic with the given ID. An unknown ID yields an
+// error wrapping fs.ErrNotExist.
+func LoadTopicByID(dir, id string) (Topic, error) {
+	topics, err := LoadAllTopics(dir)
+	if err != nil {
+		return Topic{}, err
+	}
+	for _, topic := range topics {
+		if topic.ID == id {
+			return topic, nil
+		}
+	}
+	return Topic{}, fmt.Errorf("topic %s: %w", id, fs.ErrNotExist)
+}
+
+// ensureTopicID gives a topic saved before IDs existed one derived from its
+// path, so the ID is stable until the topic is next saved and it is stored.
+func ensureTopicID(topic *Topic, rel string) {
+	if topic.ID != "" {
+		return
+	}
+	sum := sha1.Sum([]byte(filepath.ToSlash(rel)))
+	topic.ID = hex.EncodeToString(sum[:6])
+}
+
+// resolveTopicPath maps a path relative to the community directory onto an
+// absolute file path. Paths reach here straight from URLs, so anything that
+// is absolute, climbs with "..", or would otherwise resolve outside absDir is
+// rejected.
+func resolveTopicPath(absDir, relPath string) (string, error) {
+	invalid := fmt.Errorf("%w: %q", ErrInvalidTopicPath, relPath)
+
+	if relPath == "" || strings.ContainsRune(relPath, 0) {
+		return "", invalid
+	}
+	// Check both slash styles and drive letters, so a Windows path like
+	// C:\x can't pass as relative on one platform and be absolute on another
+	if filepath.IsAbs(relPath) || filepath.VolumeName(relPath) != "" ||
+		strings.HasPrefix(relPath, "/") || strings.HasPrefix(relPath, `\`) ||
+		(len(relPath) > 1 && relPath[1] == ':') {
+		return "", invalid
+	}
+
+	clean := filepath.Clean(relPath)
+	for _, elem := range strings.FieldsFunc(clean, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return "", invalid
+		}
+	}
+	if clean == "." {
+		return "", invalid
+	}
+
+	path := filepath.Join(absDir, clean)
+	if !strings.HasPrefix(path, filepath.Clean(absDir)+string(filepath.Separator)) {
+		return "", invalid
+	}
+	return path, nil
+}
+
+// InitializeIfEmpty seeds dir with the topics from the config at configPath
+// if it has no topics yet. When agentIDs are given, seeded replies by unknown
+// authors are reported.
+func InitializeIfEmpty(dir, configPath string, agentIDs ...string) error {
 	// Check if community directory is empty
-	files, err := os.ReadDir("data/community")
+	files, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Directory doesn't exist, create it
-			if err := os.MkdirAll("data/community", 0755); err != nil {
+			if err := os.MkdirAll(dir, 0755); err != nil {
 				return fmt.Errorf("creating community directory: %w", err)
 			}
 		} else {
@@ -212,26 +900,45 @@ func InitializeIfEmpty(configPath string) error {
 	}
 
 	// Load config and seed topics
-	config, err := loadConfig(configPath)
+	config, err := LoadConfig(configPath)
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
-	fmt.Printf("🌱 Seeding community with %d topics for domain: %s\n", len(config.SeedTopics), config.Domain)
+	log.Printf("community: 🌱 seeding %d topics for domain %s", len(config.SeedTopics), config.Domain)
+
+	known := make(map[string]bool, len(agentIDs))
+	for _, id := range agentIDs {
+		known[id] = true
+	}
 
 	for _, seed := range config.SeedTopics {
+		created := time.Now()
 		topic := Topic{
 			Title:     seed.Title,
 			Body:      seed.Body,
 			Author:    seed.Author,
+			Board:     seed.Board,
 			Tags:      seed.Tags,
-			Timestamp: time.Now().Format(time.RFC3339),
+			Image:     seed.Image,
+			Timestamp: created.Format(time.RFC3339),
 			Upvotes:   0,
 			Downvotes: 0,
 			Replies:   []Reply{},
 		}
 
-		if err := SaveTopic(topic, "data/community"); err != nil {
+		for i, reply := range seed.Replies {
+			if reply.Timestamp == "" {
+				// Space replies a second apart so they keep their seeded order
+				reply.Timestamp = created.Add(time.Duration(i+1) * time.Second).Format(time.RFC3339)
+			}
+			if len(known) > 0 && !known[reply.Author] {
+				log.Printf("community: seed reply on %q is by unknown agent %q", seed.Title, reply.Author)
+			}
+			topic.Replies = append(topic.Replies, reply)
+		}
+
+		if err := SaveTopic(topic, dir); err != nil {
 			return fmt.Errorf("saving seed topic: %w", err)
 		}
 	}
@@ -256,7 +963,8 @@ func loadTopic(path string) (Topic, error) {
 	return topic, nil
 }
 
-func loadConfig(path string) (Config, error) {
+// LoadConfig reads the community configuration, including its seed topics
+func LoadConfig(path string) (Config, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return Config{}, fmt.Errorf("opening config file: %w", err)
@@ -269,9 +977,34 @@ func loadConfig(path string) (Config, error) {
 		return Config{}, fmt.Errorf("decoding config JSON: %w", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config in %s:\n%w", path, err)
+	}
+
 	return config, nil
 }
 
+// Validate reports every problem with the configuration: a missing domain,
+// no seed topics, or a seed topic without a title or author.
+func (c Config) Validate() error {
+	var problems []error
+	if strings.TrimSpace(c.Domain) == "" {
+		problems = append(problems, errors.New("domain: missing"))
+	}
+	if len(c.SeedTopics) == 0 {
+		problems = append(problems, errors.New("seed_topics: at least one seed topic is required"))
+	}
+	for i, seed := range c.SeedTopics {
+		if strings.TrimSpace(seed.Title) == "" {
+			problems = append(problems, fmt.Errorf("seed_topics[%d].title: missing", i))
+		}
+		if strings.TrimSpace(seed.Author) == "" {
+			problems = append(problems, fmt.Errorf("seed_topics[%d].author: missing", i))
+		}
+	}
+	return errors.Join(problems...)
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
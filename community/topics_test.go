@@ -0,0 +1,294 @@
+package community
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentAddReplyKeepsEveryReply(t *testing.T) {
+	dir := t.TempDir()
+	topic, err := CreateTopic(dir, Topic{Title: "Concurrency", Body: "Who gets the lock?", Author: "alice", Timestamp: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reply := Reply{Author: fmt.Sprintf("agent_%d", i), Content: fmt.Sprintf("reply %d", i), Timestamp: time.Now().Format(time.RFC3339)}
+			errs <- AddReplyByPath(dir, topic.Filename, reply)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved, err := LoadTopicByRelativePath(dir, topic.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Replies) != writers {
+		t.Fatalf("saved %d replies, want %d", len(saved.Replies), writers)
+	}
+	seen := make(map[string]bool, writers)
+	for _, reply := range saved.Replies {
+		seen[reply.Content] = true
+	}
+	for i := 0; i < writers; i++ {
+		if !seen[fmt.Sprintf("reply %d", i)] {
+			t.Errorf("reply %d was lost", i)
+		}
+	}
+}
+
+func TestResolveTopicPathRejectsEscapes(t *testing.T) {
+	absDir := filepath.Join(t.TempDir(), "community")
+
+	for _, rel := range []string{
+		"",
+		".",
+		"..",
+		"../x.json",
+		"../community-sibling/x.json",
+		"../community2/x.json",
+		"a/../../x.json",
+		"a/b/../../../x.json",
+		`..\x.json`,
+		`a\..\..\x.json`,
+		"/etc/passwd",
+		`\x.json`,
+		`\\server\share\x.json`,
+		"C:x.json",
+		`C:\x.json`,
+		"c:/x.json",
+		"x.json\x00",
+		"a\x00/../x.json",
+	} {
+		if path, err := resolveTopicPath(absDir, rel); !errors.Is(err, ErrInvalidTopicPath) {
+			t.Errorf("resolveTopicPath(%q) = %q, %v; want ErrInvalidTopicPath", rel, path, err)
+		}
+	}
+}
+
+func TestResolveTopicPathAcceptsRelativePaths(t *testing.T) {
+	absDir := filepath.Join(t.TempDir(), "community")
+
+	for rel, want := range map[string]string{
+		"x.json":        "x.json",
+		"./x.json":      "x.json",
+		"sub/x.json":    filepath.Join("sub", "x.json"),
+		"sub/../x.json": "x.json",
+		"..x.json":      "..x.json",
+	} {
+		path, err := resolveTopicPath(absDir, rel)
+		if err != nil {
+			t.Errorf("resolveTopicPath(%q): %v", rel, err)
+			continue
+		}
+		if path != filepath.Join(absDir, want) {
+			t.Errorf("resolveTopicPath(%q) = %q, want %q", rel, path, filepath.Join(absDir, want))
+		}
+	}
+}
+
+func TestCreateTopicRemovesPlaceholderOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	topic := Topic{Title: "Doomed", Body: "Never saved", Author: "alice", Timestamp: "2024-05-01T09:30:00Z"}
+	name := topicFilename(topic)
+
+	// A directory in the way of the temp file makes the write fail after the
+	// name has been reserved
+	if err := os.Mkdir(filepath.Join(dir, name+".tmp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateTopic(dir, topic); err == nil {
+		t.Fatal("CreateTopic succeeded, want a write error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("placeholder %s left behind: %v", name, err)
+	}
+}
+
+func TestInitializeIfEmptyLogsSeedlingEmoji(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "config.json")
+	config := `{"domain": "cooking", "tags": [], "seed_topics": [{"title": "Welcome", "body": "Say hi", "author": "seed", "tags": []}]}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	}()
+
+	if err := InitializeIfEmpty(filepath.Join(root, "community"), configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// U+1F331 SEEDLING in UTF-8, spelled out so a re-encoded source file
+	// can't make the test agree with a broken literal
+	want := []byte("community: \xf0\x9f\x8c\xb1 seeding 1 topics for domain cooking\n")
+	if !bytes.Contains(buf.Bytes(), want) {
+		t.Errorf("log output = %q, want it to contain %q", buf.Bytes(), want)
+	}
+}
+
+// writeTopicFixtures writes n topics with timestamped file names and a few
+// replies each straight to dir, one second apart
+func writeTopicFixtures(tb testing.TB, dir string, n int) {
+	tb.Helper()
+	start := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		created := start.Add(time.Duration(i) * time.Second)
+		topic := Topic{
+			Title:     fmt.Sprintf("Topic %d", i),
+			Body:      "A body long enough to be worth decoding, repeated a few times over.",
+			Author:    "alice",
+			Timestamp: created.Format(time.RFC3339),
+			Tags:      []string{"fixture"},
+		}
+		for j := 0; j < 5; j++ {
+			topic.Replies = append(topic.Replies, Reply{Author: "bob", Content: fmt.Sprintf("Reply %d", j), Timestamp: created.Format(time.RFC3339)})
+		}
+		data, err := json.Marshal(topic)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		name := fmt.Sprintf("%s_topic_%d.json", created.Format("20060102-150405"), i)
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLoadRecentTopics compares reading the 20 newest topics from a cold
+// cache with decoding the whole community
+func BenchmarkLoadRecentTopics(b *testing.B) {
+	dir := b.TempDir()
+	writeTopicFixtures(b, dir, 3000)
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("recent", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			invalidateStore(absDir)
+			topics, err := LoadRecentTopics(dir, 20)
+			if err != nil || len(topics) != 20 {
+				b.Fatalf("got %d topics, %v", len(topics), err)
+			}
+		}
+	})
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			topics, _, err := readTopicsDir(absDir)
+			if err != nil || len(topics) != 3000 {
+				b.Fatalf("got %d topics, %v", len(topics), err)
+			}
+		}
+	})
+}
+
+func TestInitializeIfEmptySeedsReplies(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "config.json")
+	config := `{
+  "domain": "cooking",
+  "tags": [],
+  "seed_topics": [{
+    "title": "Welcome",
+    "body": "Say hi",
+    "author": "seed",
+    "tags": [],
+    "replies": [
+      {"author": "alice", "content": "Hello!", "timestamp": "2024-05-01T09:30:00Z"},
+      {"author": "bob", "content": "Hi there"},
+      {"author": "mallory", "content": "Who am I?"}
+    ]
+  }]
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	dir := filepath.Join(root, "community")
+	if err := InitializeIfEmpty(dir, configPath, "alice", "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	topics, err := LoadTopics(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 1 {
+		t.Fatalf("seeded %d topics, want 1", len(topics))
+	}
+	replies := topics[0].Replies
+	if len(replies) != 3 {
+		t.Fatalf("seeded %d replies, want 3", len(replies))
+	}
+	for i, want := range []string{"Hello!", "Hi there", "Who am I?"} {
+		if replies[i].Content != want {
+			t.Errorf("reply %d = %q, want %q", i, replies[i].Content, want)
+		}
+		if _, err := ParseTimestamp(replies[i].Timestamp); err != nil {
+			t.Errorf("reply %d timestamp: %v", i, err)
+		}
+	}
+	if replies[0].Timestamp != "2024-05-01T09:30:00Z" {
+		t.Errorf("seeded timestamp was replaced with %q", replies[0].Timestamp)
+	}
+	if !strings.Contains(buf.String(), `unknown agent "mallory"`) {
+		t.Errorf("log output = %q, want a warning about mallory", buf.String())
+	}
+}
+
+func TestCountTopicFilesIncludesNestedTopics(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "recipes")
+	if err := os.Mkdir(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTopicFile(t, dir, "top.json", Topic{Title: "Top"})
+	writeTopicFile(t, dir, "old.json", Topic{Title: "Archived", Archived: true})
+	writeTopicFile(t, nested, "nested.json", Topic{Title: "Nested"})
+
+	n, err := CountTopicFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("counted %d topic files, want 3", n)
+	}
+
+	if _, err := CountTopicFiles(filepath.Join(dir, "missing")); err == nil {
+		t.Error("counting a missing directory succeeded")
+	}
+}
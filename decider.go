@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"kommunity/agents"
+	"kommunity/community"
+)
+
+// decider chooses between starting a new topic and replying to an existing one.
+type decider struct {
+	createProb    float64 // baseline chance to create a topic
+	halfLife      float64 // average replies per recent topic at which half of the extra create chance applies; 0 disables decay
+	maxCreateProb float64 // create chance approached as recent topics become saturated
+	upvoteProb    float64 // chance to upvote a topic instead of replying
+}
+
+// createProbability returns the chance of creating a topic given the recent
+// topics. As the average reply count grows the chance climbs from createProb
+// toward maxCreateProb, so busy communities drift toward fresh threads.
+func (d decider) createProbability(topics []community.Topic) float64 {
+	if d.halfLife <= 0 || len(topics) == 0 || d.maxCreateProb <= d.createProb {
+		return d.createProb
+	}
+
+	total := 0
+	for _, t := range topics {
+		total += len(t.Replies)
+	}
+	avg := float64(total) / float64(len(topics))
+
+	saturation := 1 - math.Pow(2, -avg/d.halfLife)
+	return d.createProb + (d.maxCreateProb-d.createProb)*saturation
+}
+
+// decideAction picks "create_topic", "upvote", or "reply", drawing from rng
+// so runs with a fixed seed make the same choices. Bolder agents create more
+// often, and more empathetic ones upvote rather than reply.
+func (d decider) decideAction(rng *rand.Rand, agent agents.Agent, topics []community.Topic) string {
+	p := math.Min(1, d.createProbability(topics)*courageCreateFactor(agent.Courage))
+	if len(topics) == 0 || rng.Float64() < p {
+		return "create_topic"
+	}
+	if d.upvoteProb > 0 && rng.Float64() < math.Min(1, d.upvoteProb*empathyUpvoteFactor(agent.Empathy)) {
+		return "upvote"
+	}
+	return "reply"
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"kommunity/community"
+)
+
+// topicsWithReplies returns n topics carrying replies replies each
+func topicsWithReplies(n, replies int) []community.Topic {
+	topics := make([]community.Topic, n)
+	for i := range topics {
+		topics[i].Replies = make([]community.Reply, replies)
+	}
+	return topics
+}
+
+func TestCreateProbabilityRisesWithSaturation(t *testing.T) {
+	d := decider{createProb: 0.15, halfLife: 10, maxCreateProb: 0.5}
+
+	if got := d.createProbability(topicsWithReplies(5, 0)); got != d.createProb {
+		t.Errorf("quiet topics: got %g, want the baseline %g", got, d.createProb)
+	}
+
+	busy := d.createProbability(topicsWithReplies(5, 40))
+	if busy <= d.createProb {
+		t.Errorf("heavily replied topics: got %g, want more than the baseline %g", busy, d.createProb)
+	}
+	if busy >= d.maxCreateProb {
+		t.Errorf("heavily replied topics: got %g, want less than the ceiling %g", busy, d.maxCreateProb)
+	}
+
+	// At the half-life, half of the extra chance applies
+	half := d.createProbability(topicsWithReplies(5, 10))
+	if want := (d.createProb + d.maxCreateProb) / 2; math.Abs(half-want) > 1e-9 {
+		t.Errorf("at the half-life: got %g, want %g", half, want)
+	}
+}
+
+func TestCreateProbabilityWithoutDecay(t *testing.T) {
+	d := decider{createProb: 0.15, maxCreateProb: 0.5}
+	if got := d.createProbability(topicsWithReplies(5, 40)); got != d.createProb {
+		t.Errorf("got %g, want the baseline %g with decay off", got, d.createProb)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"kommunity/community"
+)
+
+// maxFeedItems caps the entries in an RSS feed, newest first
+const maxFeedItems = 50
+
+// rssFeed is an RSS 2.0 document with a single channel
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	Description string  `xml:"description"` // rendered HTML, escaped by the encoder
+
+	published time.Time
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// authorFeedItems lists the topics author started and the replies they wrote
+// in topics, newest first and at most maxFeedItems. Replies with an ID link
+// to their anchor on the thread page; older ones without link to the thread.
+func authorFeedItems(topics []community.Topic, author, baseURL, basePath string) []rssItem {
+	var items []rssItem
+	for _, topic := range topics {
+		link := baseURL + topicURL(basePath, topic)
+		if topic.Author == author {
+			items = append(items, newFeedItem(topic.Title, link, link, topic.Timestamp, topic.Body))
+		}
+		for i, reply := range topic.Replies {
+			if reply.Author != author {
+				continue
+			}
+			replyLink, guid := link, fmt.Sprintf("%s#%d", link, i)
+			if reply.ID != "" {
+				replyLink = link + "#reply-" + reply.ID
+				guid = replyLink
+			}
+			item := newFeedItem("Re: "+topic.Title, replyLink, guid, reply.Timestamp, reply.Content)
+			item.GUID.IsPermaLink = reply.ID != ""
+			items = append(items, item)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].published.After(items[j].published)
+	})
+	return items[:min(len(items), maxFeedItems)]
+}
+
+func newFeedItem(title, link, guid, timestamp, markdown string) rssItem {
+	item := rssItem{
+		Title:       title,
+		Link:        link,
+		GUID:        rssGUID{IsPermaLink: true, Value: guid},
+		Description: string(renderMarkdown(markdown)),
+	}
+	if published, err := community.ParseTimestamp(timestamp); err == nil {
+		item.published = published
+		item.PubDate = published.Format(time.RFC1123Z)
+	}
+	return item
+}
+
+// requestBaseURL returns the scheme and host the request was made to, for
+// the absolute links feeds need. X-Forwarded-Proto is honored for servers
+// behind a TLS-terminating proxy.
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// writeRSS renders feed as an RSS document
+func writeRSS(c *gin.Context, feed rssFeed) {
+	feed.Version = "2.0"
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to render feed: %v", err)
+		return
+	}
+	c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", append([]byte(xml.Header), out...))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+
+	"kommunity/ollama"
+	"kommunity/openai"
+)
+
+// Generator produces model output for a request. The request's System,
+// Prompt, and Options are understood by every backend. Model is empty unless
+// an agent names its own, so each backend otherwise uses its configured model.
+type Generator interface {
+	Generate(ctx context.Context, req ollama.Request) (string, error)
+}
+
+// generatorFunc adapts a plain function to Generator
+type generatorFunc func(ctx context.Context, req ollama.Request) (string, error)
+
+// Generate calls f
+func (f generatorFunc) Generate(ctx context.Context, req ollama.Request) (string, error) {
+	return f(ctx, req)
+}
+
+// openaiGenerator sends requests to an OpenAI-compatible chat completions API
+type openaiGenerator struct {
+	client *openai.Client
+}
+
+// Generate maps req onto a chat request, sending System as the system message
+func (g openaiGenerator) Generate(ctx context.Context, req ollama.Request) (string, error) {
+	chat := openai.ChatRequest{Model: req.Model}
+	if req.System != "" {
+		chat.Messages = append(chat.Messages, openai.Message{Role: "system", Content: req.System})
+	}
+	chat.Messages = append(chat.Messages, openai.Message{Role: "user", Content: req.Prompt})
+	if opts := req.Options; opts != nil {
+		chat.Temperature = opts.Temperature
+		chat.TopP = opts.TopP
+		chat.Seed = opts.Seed
+		chat.MaxTokens = opts.NumPredict
+	}
+	return g.client.Chat(ctx, chat)
+}
+
+// newGenerator returns the generator for backend ("ollama" or "openai").
+// Streaming to the console is only available with Ollama.
+func newGenerator(backend string, stream bool) (Generator, error) {
+	switch backend {
+	case "ollama":
+		if stream {
+			return generatorFunc(streamToConsole), nil
+		}
+		return generatorFunc(ollama.GenerateRequest), nil
+	case "openai":
+		if stream {
+			fmt.Fprintln(os.Stderr, "⚠️  -stream is only supported with the ollama backend; ignoring it")
+		}
+		return openaiGenerator{client: openai.NewClientFromEnv()}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q (want ollama or openai)", backend)
+	}
+}
+
+// stubGenerator returns deterministic placeholder text without calling a
+// model, for exercising the simulation loop with -dry-run. The same seed
+// yields the same sequence of posts.
+type stubGenerator struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+	n   int
+}
+
+func newStubGenerator(seed int64) *stubGenerator {
+	return &stubGenerator{rng: rand.New(rand.NewSource(seed))}
+}
+
+var loremWords = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua")
+
+// Generate answers rating prompts with a passing score, topic picks with the
+// first topic, tag prompts with two lorem words, and everything else with
+// numbered lorem ipsum attributed to the persona in req.System
+func (g *stubGenerator) Generate(ctx context.Context, req ollama.Request) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(req.Prompt, "Rate the following"):
+		return "5", nil
+	case strings.HasPrefix(req.Prompt, "Pick"):
+		return "1", nil
+	case strings.HasPrefix(req.Prompt, "Summarize this discussion in 3 bullet points"):
+		return "- " + g.lorem(8) + "\n- " + g.lorem(8) + "\n- " + g.lorem(8), nil
+	case strings.HasPrefix(req.Prompt, "Summarize"):
+		return "Lorem summary of the discussion so far.", nil
+	case strings.HasPrefix(req.Prompt, "Suggest"):
+		return loremWords[g.rng.Intn(len(loremWords))] + ", " + loremWords[g.rng.Intn(len(loremWords))], nil
+	}
+
+	g.n++
+	// Custom create_topic templates may word things differently, but they
+	// still ask for the Title:/Body: format
+	if strings.HasPrefix(req.Prompt, "Create") || strings.Contains(req.Prompt, "\nTitle:") {
+		// Random title words keep placeholder topics apart for the duplicate check
+		return fmt.Sprintf("Title: Lorem topic #%d by %s: %s\nBody: %s.", g.n, personaName(req.System), g.lorem(4), g.lorem(16)), nil
+	}
+	return fmt.Sprintf("Lorem reply #%d by %s: %s.", g.n, personaName(req.System), g.lorem(8)), nil
+}
+
+// lorem returns n random lorem ipsum words
+func (g *stubGenerator) lorem(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = loremWords[g.rng.Intn(len(loremWords))]
+	}
+	return strings.Join(words, " ")
+}
+
+// personaName extracts the agent name from a system message built by persona
+func personaName(system string) string {
+	name, _, found := strings.Cut(strings.TrimPrefix(system, "You are "), ",")
+	if !found || name == "" {
+		return "anonymous"
+	}
+	return name
+}
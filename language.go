@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"kommunity/agents"
+)
+
+// languageNames maps the codes -language accepts to the names the model is
+// asked to write in
+var languageNames = map[string]string{
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"zh": "Chinese",
+}
+
+// parseLanguage lowercases a -language code and checks that it is known.
+// Regional variants such as pt-BR are kept as given but named after their
+// base language. An empty code means English without recording it.
+func parseLanguage(code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return "", nil
+	}
+	if languageName(code) == "" {
+		known := make([]string, 0, len(languageNames))
+		for c := range languageNames {
+			known = append(known, c)
+		}
+		sort.Strings(known)
+		return "", fmt.Errorf("unknown language %q (known: %s)", code, strings.Join(known, ", "))
+	}
+	return code, nil
+}
+
+// languageName returns the English name of a language code, or "" if unknown
+func languageName(code string) string {
+	base, _, _ := strings.Cut(code, "-")
+	return languageNames[base]
+}
+
+// languageInstruction asks the model to write in the given language; English
+// needs no instruction, keeping prompts as they were before -language
+func languageInstruction(code string, topic bool) string {
+	name := languageName(code)
+	if name == "" || name == "English" {
+		return ""
+	}
+	if topic {
+		// The labels are how the answer is parsed, so they stay in English
+		return fmt.Sprintf(" Write the title and body in %s, but keep the Title: and Body: labels in English.", name)
+	}
+	return fmt.Sprintf(" Respond in %s.", name)
+}
+
+// languageFor returns the language an agent writes in. Every agent uses the
+// run's -language for now; a per-agent setting would take precedence here.
+func (s *simulator) languageFor(agent agents.Agent) string {
+	return s.language
+}
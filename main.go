@@ -1,10 +1,22 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"math"
 	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"kommunity/agents"
@@ -15,10 +27,176 @@ import (
 func main() {
 	serve := flag.Bool("serve", false, "start the web interface")
 	addr := flag.String("addr", ":8080", "address for the web interface")
+	basePath := flag.String("base-path", "", "URL prefix for the web interface when served behind a reverse proxy (e.g. /kommunity)")
+	simulate := flag.Bool("simulate", false, "with -serve, also run the agent loop so the site updates live")
+	once := flag.Bool("once", false, "run a single turn for a random agent and exit, non-zero if it fails; for cron and other external schedulers")
+	simulateStep := flag.Bool("simulate-step", false, "with -serve, enable POST /simulate/step to run one agent turn per request")
+	summaries := flag.Bool("summaries", false, "with -serve, enable GET /topic/<id>/summary to summarize a thread with the model")
+	dev := flag.Bool("dev", false, "reload web templates when they change on disk")
+	corsOrigin := flag.String("cors-origin", "", "comma-separated origins allowed to call /api from a browser (\"*\" for any); empty disables CORS")
+	maxBodyBytes := flag.Int64("max-body-bytes", 1<<20, "maximum request body size in bytes for web write endpoints")
+	createProb := flag.Float64("create-prob", 0.15, "baseline probability (0-1) that an agent starts a new topic instead of replying")
+	replyHalfLife := flag.Float64("reply-half-life", 10, "average replies per recent topic at which half of the extra create chance applies (0 disables)")
+	maxCreateProb := flag.Float64("max-create-prob", 0.5, "create-topic probability approached when recent topics are saturated with replies")
+	upvoteProb := flag.Float64("upvote-prob", 0.1, "probability (0-1) that an agent upvotes the recent topic it finds most relevant instead of replying")
+	blocklistPath := flag.String("blocklist", "", "file of banned terms, one per line; generated posts containing one are not saved (defaults to blocklist.txt in the data directory)")
+	qualityGateOn := flag.Bool("quality-gate", false, "ask the model to rate each generation and regenerate low-quality output")
+	qualityMin := flag.Int("quality-min", 3, "minimum quality score (1-5) accepted by the quality gate")
+	qualityAttempts := flag.Int("quality-attempts", 3, "maximum generations per post when the quality gate is enabled")
+	summarizeOld := flag.Bool("summarize-old-context", false, "summarize older replies in reply prompts instead of including them verbatim")
+	contextReplies := flag.Int("context-replies", 20, "most recent replies included in reply prompts; older ones are left out (0 includes all)")
+	contextChars := flag.Int("context-chars", 16000, "character budget for the thread in reply prompts, keeping the newest replies that fit; the default suits 8k-token models (0 is unlimited)")
+	vision := flag.Bool("vision", false, "show a topic's image to the model when replying to it (needs a multimodal model such as llava)")
+	replyCooldown := flag.Int("reply-cooldown", 1, "agents skip topics where they wrote one of the last this many replies (0 disables)")
+	maxReplies := flag.Int("max-replies", 0, "treat topics with this many replies as closed so agents stop replying to them (0 is unlimited)")
+	recentReplies := flag.Int("recent-replies", 5, "replies kept verbatim in reply prompts when summarizing older context")
+	nestedReplyProb := flag.Float64("nested-reply-prob", 0.3, "probability that a reply answers a specific earlier comment rather than the topic")
+	traitTemperature := flag.Bool("trait-temperature", false, "derive each agent's sampling temperature from its courage trait")
+	agentSeeds := flag.Bool("agent-seeds", false, "use a deterministic per-agent sampling seed for consistent persona voices")
+	ollamaTimeout := flag.Duration("ollama-timeout", 60*time.Second, "maximum time to wait for a single model response, retries and waits for a free slot included (0 waits indefinitely)")
+	ollamaRPS := flag.Float64("ollama-rps", 0, "maximum Ollama generate requests per second across all agents; calls wait for a slot (0 is unlimited)")
+	ollamaMaxConcurrent := flag.Int("ollama-max-concurrent", 0, "maximum Ollama generate requests in flight at once, from agents and web summaries alike (0 is unlimited)")
+	promptBudget := flag.Int64("prompt-budget", 0, "stop the run once this many prompt characters have been sent to the model (0 is unlimited)")
+	ollamaWait := flag.Duration("ollama-wait", 30*time.Second, "how long to wait at startup for Ollama to come up, retrying with backoff (0 checks once)")
+	requireModel := flag.Bool("require-model", false, "exit at startup if the configured model is not installed in Ollama")
+	stream := flag.Bool("stream", false, "print model output token by token as it is generated")
+	dryRun := flag.Bool("dry-run", false, "generate deterministic placeholder text instead of calling a model; everything else runs as usual")
+	backend := flag.String("backend", "ollama", "model backend: ollama, or openai for an OpenAI-compatible API configured by OPENAI_BASE_URL, OPENAI_API_KEY, and OPENAI_MODEL")
+	maxTopics := flag.Int("max-community-topics", 0, "archive the least active topics once the community exceeds this many (0 disables)")
+	dedupThreshold := flag.Float64("dedup-threshold", 0.9, "similarity (0-1) to a recent topic title at which a generated topic is discarded as a duplicate (0 disables)")
+	memorySize := flag.Int("memory-size", 3, "recent topics each agent remembers touching and steers away from (0 disables)")
+	seed := flag.Int64("seed", 0, "seed for agent, action, and topic choices so runs are reproducible (0 picks a random seed)")
+	concurrency := flag.Int("concurrency", 1, "agents that act at the same time each turn, overlapping their model calls")
+	interval := flag.Duration("interval", 5*time.Second, "pause between agent turns")
+	jitter := flag.Duration("jitter", 0, "maximum random extra pause added to -interval each turn (e.g. 30s)")
+	fixTimestamps := flag.Bool("fix-timestamps", false, "backfill missing or invalid topic timestamps and exit")
+	normalizeAuthors := flag.Bool("normalize-authors", false, "rewrite topic and reply authors given by agent name to the agent ID and exit")
+	dataDir := flag.String("data-dir", envOr("KOMMUNITY_DATA_DIR", "data"), "directory holding agents.json (or agents/), config.json, memory.json, and the community/ topics (defaults to $KOMMUNITY_DATA_DIR or data)")
+	migrateFilenames := flag.Bool("migrate-filenames", false, "rename topic files to the timestamp-and-title scheme, update agent memory, and exit")
+	exportPath := flag.String("export", "", "write every topic with its replies to this NDJSON file (- for stdout) and exit")
+	importPath := flag.String("import", "", "save the topics from an NDJSON export into the community directory and exit")
+	language := flag.String("language", "", "language code for generated posts, e.g. it or es; recorded on each post (default English)")
+	validate := flag.Bool("validate", false, "check agents, config, prompts, and every topic file for problems, print them, and exit non-zero if any are found")
+	preview := flag.Bool("preview", false, "with maintenance modes, report changes without writing files")
 	flag.Parse()
 
-	if *serve {
-		if err := runServer(*addr); err != nil {
+	for name, p := range map[string]float64{"create-prob": *createProb, "max-create-prob": *maxCreateProb, "upvote-prob": *upvoteProb, "dedup-threshold": *dedupThreshold} {
+		if p < 0 || p > 1 {
+			log.Fatalf("-%s must be between 0 and 1, got %g", name, p)
+		}
+	}
+	if *ollamaRPS < 0 {
+		log.Fatalf("-ollama-rps must not be negative, got %g", *ollamaRPS)
+	}
+	if *ollamaMaxConcurrent < 0 {
+		log.Fatalf("-ollama-max-concurrent must not be negative, got %d", *ollamaMaxConcurrent)
+	}
+	if *concurrency < 1 {
+		log.Fatalf("-concurrency must be at least 1, got %d", *concurrency)
+	}
+	if *once && *serve {
+		log.Fatalf("-once runs a single turn and exits, so it can't be combined with -serve")
+	}
+
+	paths := dataPaths{root: *dataDir}
+
+	if *validate {
+		if validateData(paths) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *fixTimestamps {
+		fixed, err := community.FixTimestamps(paths.community(), *preview)
+		if err != nil {
+			log.Fatalf("failed to fix timestamps: %v", err)
+		}
+		if *preview {
+			fmt.Printf("🕰️  %d topics would be fixed\n", fixed)
+		} else {
+			fmt.Printf("🕰️  Fixed %d topics\n", fixed)
+		}
+		return
+	}
+
+	if *normalizeAuthors {
+		agentList, err := paths.loadAgents()
+		if err != nil {
+			log.Fatalf("failed to load agents: %v", err)
+		}
+		updated, err := community.MigrateAuthors(paths.community(), agents.NameIndex(agentList), *preview)
+		if err != nil {
+			log.Fatalf("failed to normalize authors: %v", err)
+		}
+		if *preview {
+			fmt.Printf("🪪 %d topics would be updated\n", updated)
+		} else {
+			fmt.Printf("🪪 Updated %d topics\n", updated)
+		}
+		return
+	}
+
+	if *migrateFilenames {
+		renames, err := community.MigrateFilenames(paths.community(), *preview)
+		if err != nil {
+			log.Fatalf("failed to migrate filenames: %v", err)
+		}
+		if *preview {
+			fmt.Printf("📛 %d topics would be renamed\n", len(renames))
+			return
+		}
+
+		memory, err := agents.LoadMemory(paths.memory(), *memorySize)
+		if err != nil {
+			log.Fatalf("failed to load agent memory: %v", err)
+		}
+		if memory.RenameTopics(renames) {
+			if err := memory.Save(paths.memory()); err != nil {
+				log.Fatalf("failed to save agent memory: %v", err)
+			}
+		}
+		fmt.Printf("📛 Renamed %d topics\n", len(renames))
+		return
+	}
+
+	if *exportPath != "" {
+		n, err := exportTopics(paths.community(), *exportPath)
+		if err != nil {
+			log.Fatalf("failed to export topics: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "📦 Exported %d topics\n", n)
+		return
+	}
+
+	if *importPath != "" {
+		file, err := os.Open(*importPath)
+		if err != nil {
+			log.Fatalf("failed to open import file: %v", err)
+		}
+		defer file.Close()
+		n, err := community.ImportTopics(paths.community(), bufio.NewReader(file))
+		if err != nil {
+			log.Fatalf("failed to import topics after %d: %v", n, err)
+		}
+		fmt.Printf("📦 Imported %d topics\n", n)
+		return
+	}
+
+	serverOpts := serverOptions{
+		addr:         *addr,
+		basePath:     *basePath,
+		maxBodyBytes: *maxBodyBytes,
+		dev:          *dev,
+		data:         paths,
+		corsOrigins:  parseOrigins(*corsOrigin),
+	}
+
+	// A read-only viewer needs no model or agents set up
+	if *serve && !*simulateStep && !*simulate && !*summaries {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := runServer(ctx, serverOpts); err != nil {
 			log.Fatalf("failed to start web server: %v", err)
 		}
 		return
@@ -26,8 +204,61 @@ func main() {
 
 	fmt.Println("🚀 Starting Kommunity Simulator...")
 
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	fmt.Printf("Using random seed %d\n", *seed)
+	rng := rand.New(newLockedSource(*seed))
+
+	var generator Generator
+	if *dryRun {
+		generator = newStubGenerator(*seed)
+		fmt.Println("Dry run: generating placeholder text instead of calling a model")
+	} else {
+		var err error
+		if generator, err = newGenerator(*backend, *stream); err != nil {
+			log.Fatalf("invalid -backend: %v", err)
+		}
+		if *backend == "ollama" {
+			ollama.SetRateLimit(*ollamaRPS, 1)
+			ollama.SetMaxConcurrent(*ollamaMaxConcurrent)
+			ollama.SetTimeout(*ollamaTimeout)
+			if model := os.Getenv("OLLAMA_MODEL"); model != "" {
+				ollama.SetDefaultModel(model)
+			}
+			fmt.Printf("Using model %s\n", ollama.GetDefaultModel())
+			if !ollama.IsOllamaRunning() && *ollamaWait > 0 {
+				fmt.Printf("⏳ Waiting for Ollama to come up (up to %s)...\n", *ollamaWait)
+				if err := ollama.WaitUntilReady(context.Background(), *ollamaWait); err != nil {
+					fmt.Printf("⚠️  %v\n", err)
+				} else {
+					fmt.Println("✅ Ollama is ready")
+				}
+			}
+			if err := checkModel(ollama.GetDefaultModel()); err != nil {
+				if *requireModel {
+					log.Fatalf("model check failed: %v", err)
+				}
+				fmt.Printf("⚠️  %v\n", err)
+			}
+		} else {
+			fmt.Printf("Using %s backend\n", *backend)
+		}
+	}
+
+	if *blocklistPath == "" {
+		*blocklistPath = paths.blocklist()
+	}
+	blocked, err := loadBlocklist(*blocklistPath)
+	if err != nil {
+		log.Fatalf("failed to load blocklist: %v", err)
+	}
+	if blocked != nil {
+		fmt.Printf("Moderating generated posts with %s\n", *blocklistPath)
+	}
+
 	// Load agents
-	agentList, err := agents.LoadAgents("data/agents.json")
+	agentList, err := paths.loadAgents()
 	if err != nil {
 		fmt.Printf("Error loading agents: %v\n", err)
 		return
@@ -35,135 +266,800 @@ func main() {
 
 	fmt.Printf("Loaded %d agents\n", len(agentList))
 
+	// Agents with a model of their own need it installed as much as the default
+	checked := map[string]bool{ollama.GetDefaultModel(): true}
+	for _, a := range agentList {
+		if a.Model == "" {
+			continue
+		}
+		fmt.Printf("%s writes with model %s\n", a.Name, a.Model)
+		if *dryRun || *backend != "ollama" || checked[a.Model] {
+			continue
+		}
+		checked[a.Model] = true
+		if err := checkModel(a.Model); err != nil {
+			if *requireModel {
+				log.Fatalf("model check failed: %v", err)
+			}
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	}
+
+	agentIDs := make([]string, 0, len(agentList))
+	for _, a := range agentList {
+		agentIDs = append(agentIDs, a.ID)
+	}
+
+	memory, err := agents.LoadMemory(paths.memory(), *memorySize)
+	if err != nil {
+		fmt.Printf("Error loading agent memory: %v\n", err)
+		return
+	}
+
 	// Initialize community if empty
-	if err := community.InitializeIfEmpty("data/config.json"); err != nil {
+	if err := community.InitializeIfEmpty(paths.community(), paths.config(), agentIDs...); err != nil {
 		fmt.Printf("Error initializing community: %v\n", err)
 		return
 	}
 
+	// Once the community is seeded the config only offers a tag vocabulary,
+	// so a missing file is fine, but a broken one should be fixed
+	config, err := community.LoadConfig(paths.config())
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("⚠️  Loading community config: %v\n", err)
+	} else if err != nil {
+		fmt.Printf("Error loading community config: %v\n", err)
+		return
+	}
+
+	prompts, err := loadPrompts(paths.prompts())
+	if err != nil {
+		fmt.Printf("Error loading prompts: %v\n", err)
+		return
+	}
+	lang, err := parseLanguage(*language)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	sim := &simulator{
+		rng:     rng,
+		timeout: *ollamaTimeout,
+		decider: decider{
+			createProb:    *createProb,
+			halfLife:      *replyHalfLife,
+			maxCreateProb: *maxCreateProb,
+			upvoteProb:    *upvoteProb,
+		},
+		blocklist: blocked,
+		quality: qualityGate{
+			enabled:     *qualityGateOn,
+			minScore:    *qualityMin,
+			maxAttempts: *qualityAttempts,
+		},
+		replyContext: contextOptions{
+			summarizeOld:  *summarizeOld,
+			recentReplies: *recentReplies,
+			maxReplies:    *contextReplies,
+			maxChars:      *contextChars,
+		},
+		maxReplies:       *maxReplies,
+		replyCooldown:    *replyCooldown,
+		nestedReplyProb:  *nestedReplyProb,
+		agentSeeds:       *agentSeeds,
+		traitTemperature: *traitTemperature,
+		aliases:          agents.NameIndex(agentList),
+		agentsByID:       agents.IDIndex(agentList),
+		maxTopics:        *maxTopics,
+		memory:           memory,
+		communityDir:     paths.community(),
+		memoryPath:       paths.memory(),
+		tagVocabulary:    config.Tags,
+		boards:           config.Boards,
+		domain:           config.Domain,
+		prompts:          prompts,
+		language:         lang,
+		dedupThreshold:   *dedupThreshold,
+		promptBudget:     *promptBudget,
+	}
+	if *vision {
+		sim.imagesDir = paths.images()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// One turn per invocation leaves the pacing to cron or a systemd timer
+	if *once {
+		result, err := sim.performAgentAction(ctx, generator, pickAgents(rng, agentList, 1)[0])
+		if err != nil {
+			log.Fatalf("agent %s failed: %v", result.Agent, err)
+		}
+		fmt.Printf("👋 Done: %s did %s\n", result.Agent, result.Action)
+		return
+	}
+
+	if *simulateStep {
+		serverOpts.step = func(ctx context.Context) (actionResult, error) {
+			return sim.performAgentAction(ctx, generator, pickAgents(rng, agentList, 1)[0])
+		}
+	}
+	if *summaries {
+		cache := newThreadSummaries()
+		serverOpts.summarize = func(ctx context.Context, topic community.Topic) (string, bool, error) {
+			return cache.get(ctx, topic, func(ctx context.Context) (string, error) {
+				return sim.summarizeThread(ctx, generator, topic)
+			})
+		}
+	}
+	if *serve && !*simulate {
+		if err := runServer(ctx, serverOpts); err != nil {
+			log.Fatalf("failed to start web server: %v", err)
+		}
+		return
+	}
+
+	// With -serve -simulate the site is served while the loop runs, and both
+	// stop on the same signal
+	serverDone := make(chan struct{})
+	close(serverDone)
+	if *serve {
+		serverDone = make(chan struct{})
+		go func() {
+			defer close(serverDone)
+			if err := runServer(ctx, serverOpts); err != nil {
+				fmt.Printf("Error running web server: %v\n", err)
+				stop()
+			}
+		}()
+	}
+	defer func() {
+		stop()
+		<-serverDone
+	}()
+
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-finished: // the run ended on its own
+			return
+		}
+		// Restore default signal handling so a second Ctrl+C exits immediately
+		stop()
+		fmt.Println("\n🛑 Stopping after the current action... (Ctrl+C again to force quit)")
+	}()
+
 	// Main simulation loop
 	fmt.Println("🎭 Simulation starting... (Ctrl+C to stop)")
-	for {
-		// Select random agent
-		agent := agentList[rand.Intn(len(agentList))]
+	for ctx.Err() == nil {
+		// Agents perform their actions; a shutdown signal lets them finish
+		// rather than abandoning a model call or a save halfway
+		sim.runTurn(context.WithoutCancel(ctx), generator, pickAgents(rng, agentList, *concurrency))
+		if sim.budgetSpent() {
+			fmt.Printf("💸 Prompt budget of %d characters used up, stopping\n", *promptBudget)
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(turnDelay(rng, *interval, *jitter)):
+		}
+	}
+
+	fmt.Printf("👋 Session over: %d topics and %d replies created, %d upvotes cast\n", sim.session.topics.Load(), sim.session.replies.Load(), sim.session.votes.Load())
+}
+
+// exportTopics writes the community as NDJSON to path, or to stdout for "-"
+func exportTopics(dir, path string) (int, error) {
+	out := os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return 0, fmt.Errorf("creating export file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
 
-		// Agent performs action
-		if err := performAgentAction(agent); err != nil {
-			fmt.Printf("Agent %s error: %v\n", agent.Name, err)
+	w := bufio.NewWriter(out)
+	n, err := community.ExportTopics(dir, w)
+	if err != nil {
+		return n, err
+	}
+	if err := w.Flush(); err != nil {
+		return n, fmt.Errorf("writing export: %w", err)
+	}
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			return n, fmt.Errorf("closing export file: %w", err)
 		}
+	}
+	return n, nil
+}
+
+// loadTopicImage reads a topic's image from dir. Names come from topic files,
+// so anything that isn't a plain file name in dir is refused.
+func loadTopicImage(dir, name string) ([]byte, error) {
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid image name %q", name)
+	}
+	img, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("reading topic image: %w", err)
+	}
+	return img, nil
+}
+
+// pickAgents chooses n distinct agents at random, or every agent in random
+// order when n is not smaller than the list
+func pickAgents(rng *rand.Rand, agentList []agents.Agent, n int) []agents.Agent {
+	if n == 1 {
+		return []agents.Agent{agentList[rng.Intn(len(agentList))]}
+	}
+	picked := make([]agents.Agent, 0, min(n, len(agentList)))
+	for _, i := range rng.Perm(len(agentList))[:min(n, len(agentList))] {
+		picked = append(picked, agentList[i])
+	}
+	return picked
+}
+
+// runTurn lets each agent act at the same time and waits for all of them. A
+// panic in one agent's action is reported without stopping the others.
+func (s *simulator) runTurn(ctx context.Context, gen Generator, turn []agents.Agent) {
+	var wg sync.WaitGroup
+	for _, agent := range turn {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Printf("Agent %s panicked: %v\n", agent.Name, r)
+				}
+			}()
+
+			if _, err := s.performAgentAction(ctx, gen, agent); err != nil {
+				if errors.Is(err, context.DeadlineExceeded) {
+					fmt.Printf("Agent %s timed out waiting for the model: %v\n", agent.Name, err)
+				} else {
+					fmt.Printf("Agent %s error: %v\n", agent.Name, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// lockedSource is a rand.Source safe for the concurrent agents of a turn
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+func (l *lockedSource) Int63() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.src.Int63()
+}
+
+func (l *lockedSource) Uint64() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.src.Uint64()
+}
+
+func (l *lockedSource) Seed(seed int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.src.Seed(seed)
+}
+
+// turnDelay returns the pause before the next agent turn: interval plus a
+// random amount below jitter
+func turnDelay(rng *rand.Rand, interval, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rng.Int63n(int64(jitter)))
+}
 
-		// Sleep with jitter
-		// sleepDuration := time.Duration(rand.Intn(30)+30) * time.Second
-		sleepDuration := 5 * time.Second
-		time.Sleep(sleepDuration)
+// checkModel confirms that Ollama is reachable and has model installed
+func checkModel(model string) error {
+	models, err := ollama.ListModels()
+	if err != nil {
+		return fmt.Errorf("could not list Ollama models: %w", err)
+	}
+	if !ollama.HasModel(models, model) {
+		return fmt.Errorf("model %s is not installed; run `ollama pull %s`", model, model)
 	}
+	return nil
+}
+
+// simulator carries the settings that shape each agent turn
+type simulator struct {
+	rng              *rand.Rand    // source for every random choice, seeded by -seed
+	timeout          time.Duration // per-request limit for model calls; 0 waits indefinitely
+	decider          decider
+	quality          qualityGate
+	blocklist        *blocklist // banned terms; nil saves everything
+	replyContext     contextOptions
+	nestedReplyProb  float64                 // chance a reply answers an earlier comment instead of the topic
+	agentSeeds       bool                    // pass a per-agent sampling seed so each persona keeps a steady voice
+	traitTemperature bool                    // bolder agents sample at higher temperatures
+	aliases          map[string]string       // agent display names to IDs, for normalizing authors
+	agentsByID       map[string]agents.Agent // every loaded agent, for resolving @mentions
+	maxTopics        int                     // archive the least active topics beyond this many; 0 disables
+	maxReplies       int                     // topics with this many replies are closed to agents; 0 is unlimited
+	replyCooldown    int                     // agents skip threads where they wrote one of the last this many replies; 0 disables
+	imagesDir        string                  // topic images are sent to the model from here; empty keeps prompts text-only
+	memory           *agents.Memory          // topics each agent recently touched
+	communityDir     string
+	memoryPath       string
+	tagVocabulary    []string // community tags suggested when tagging new topics
+	boards           []string // community boards new topics are filed under
+	domain           string   // the community's subject, from config.json
+	prompts          promptSet
+	language         string  // code of the language posts are written in; empty is English
+	dedupThreshold   float64 // title similarity at which a new topic is dropped; 0 disables
+	promptBudget     int64   // prompt characters the run may send; 0 is unlimited
+
+	session sessionStats
+}
+
+// actionResult records what an agent did on its turn
+type actionResult struct {
+	Agent  string `json:"agent"`
+	Action string `json:"action"`
+	Topic  string `json:"topic,omitempty"` // path of the topic created, replied to, or upvoted
+}
+
+// sessionStats counts what the simulator created since it started
+type sessionStats struct {
+	topics      atomic.Int64
+	replies     atomic.Int64
+	votes       atomic.Int64
+	promptChars atomic.Int64 // system and user prompt characters sent to the model
+}
+
+// errPromptBudget is returned for model calls refused because the run's
+// prompt budget is used up
+var errPromptBudget = errors.New("prompt budget exhausted")
+
+// budgetSpent reports whether the prompt budget has been used up
+func (s *simulator) budgetSpent() bool {
+	return s.promptBudget > 0 && s.session.promptChars.Load() >= s.promptBudget
 }
 
-func performAgentAction(agent agents.Agent) error {
+// performAgentAction runs one turn for agent, producing any text with gen
+func (s *simulator) performAgentAction(ctx context.Context, gen Generator, agent agents.Agent) (actionResult, error) {
 	fmt.Printf("🤖 %s (%s) is thinking...\n", agent.Name, agent.Style)
+	result := actionResult{Agent: agent.ID}
 
 	// Load recent topics
-	topics, err := community.LoadRecentTopics("data/community", 5)
+	topics, err := community.LoadRecentTopics(s.communityDir, 5)
 	if err != nil {
-		return fmt.Errorf("loading topics: %w", err)
+		return result, fmt.Errorf("loading topics: %w", err)
+	}
+	for i := range topics {
+		community.NormalizeAuthors(&topics[i], s.aliases)
 	}
 
 	fmt.Printf("   📚 Found %d recent topics\n", len(topics))
 
-	// Decide action (simplified for now)
-	action := decideAction(agent, topics)
-	fmt.Printf("   🎯 Decided to: %s\n", action)
+	// Steer away from threads this agent just took part in; when it has
+	// touched all of them, it starts a new one instead
+	topics = s.untouchedTopics(agent, topics)
+	topics = s.openTopics(topics)
+	topics = s.repliableTopics(agent, topics)
+	topics = s.boardTopics(agent, topics)
 
-	switch action {
+	// Decide action, biased toward new topics when recent ones are saturated
+	result.Action = s.decider.decideAction(s.rng, agent, topics)
+	fmt.Printf("   🎯 Decided to: %s\n", result.Action)
+
+	switch result.Action {
 	case "create_topic":
-		return createNewTopic(agent)
+		result.Topic, err = s.createNewTopic(ctx, gen, agent)
+	case "upvote":
+		result.Topic, err = s.upvoteTopic(ctx, gen, agent, topics)
 	case "reply":
-		if len(topics) > 0 {
-			// Select a random topic from recent ones to encourage broader participation
-			selectedTopic := topics[rand.Intn(len(topics))]
-			fmt.Printf("   🎲 Selected topic for reply: '%s' (by %s)\n", selectedTopic.Title[:min(50, len(selectedTopic.Title))]+"...", selectedTopic.Author)
-			return replyToTopic(agent, selectedTopic)
+		// Select a recent topic at random, steered toward quiet threads by empathy
+		selectedTopic := pickReplyTopic(s.rng, agent, topics)
+		fmt.Printf("   🎲 Selected topic for reply: '%s' (by %s, %s)\n", selectedTopic.Title[:min(50, len(selectedTopic.Title))]+"...", selectedTopic.Author, selectedTopic.Filename)
+		// Sometimes quote one earlier reply and answer it directly
+		quoted := s.pickParentReply(selectedTopic)
+		if quoted != nil {
+			fmt.Printf("   ↪️  Quoting %s's reply\n", quoted.Author)
+		}
+		if err = s.replyToTopic(ctx, gen, agent, selectedTopic, quoted); err == nil {
+			result.Topic = filepath.ToSlash(selectedTopic.Filename)
 		}
 	}
 
-	return nil
+	return result, err
 }
 
-func decideAction(agent agents.Agent, topics []community.Topic) string {
-	// Enhanced decision logic - 15% chance to create, 85% to reply if topics exist
-	// This encourages more conversation depth
-	if len(topics) == 0 || rand.Float64() < 0.15 {
-		return "create_topic"
+// complete sends a prompt to model, or to the backend's default model when
+// it is empty, bounded by the configured timeout. A non-empty system is sent
+// as the system message, and images go along for multimodal models. Once the
+// prompt budget is used up it refuses with errPromptBudget.
+func (s *simulator) complete(ctx context.Context, gen Generator, model, system, prompt string, opts ollama.Options, images ...[]byte) (string, error) {
+	if s.budgetSpent() {
+		return "", errPromptBudget
+	}
+	s.session.promptChars.Add(int64(len(system) + len(prompt)))
+
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
 	}
-	return "reply"
+
+	req := ollama.Request{Model: model, Prompt: prompt, System: system, Images: ollama.EncodeImages(images)}
+	if opts != (ollama.Options{}) {
+		req.Options = &opts
+	}
+	return gen.Generate(ctx, req)
+}
+
+// streamToConsole generates a response while echoing tokens as they arrive
+func streamToConsole(ctx context.Context, req ollama.Request) (string, error) {
+	fmt.Print("   ⌨️  ")
+	content, err := ollama.StreamRequest(ctx, req, func(token string) error {
+		fmt.Print(token)
+		return nil
+	})
+	fmt.Println()
+	return content, err
+}
+
+// generationOptions returns the sampling options used for an agent's posts
+func (s *simulator) generationOptions(agent agents.Agent) ollama.Options {
+	var opts ollama.Options
+	if s.agentSeeds {
+		opts.Seed = agentSeed(agent.ID)
+	}
+	if s.traitTemperature {
+		temperature := courageTemperature(agent.Courage)
+		opts.Temperature = &temperature
+	}
+	return opts
+}
+
+// courageTemperature maps courage (0..1) onto a sampling temperature between
+// 0.5 for cautious agents and 1.2 for daring ones
+func courageTemperature(courage float64) float64 {
+	courage = math.Max(0, math.Min(1, courage))
+	return 0.5 + 0.7*courage
+}
+
+// persona describes an agent for the system message, so it stays in
+// character however long the thread in the user prompt gets
+func persona(agent agents.Agent) string {
+	p := fmt.Sprintf("You are %s, %s. You are a member of an online discussion community.", agent.Name, agent.Style)
+	if guidance := traitGuidance(agent); guidance != "" {
+		p += " " + guidance
+	}
+	return p + " Stay in character."
 }
 
-func createNewTopic(agent agents.Agent) error {
-	prompt := fmt.Sprintf("You are %s, %s. Create an interesting discussion topic for our community. Keep it to 1-2 sentences.", agent.Name, agent.Style)
+// agentSeed derives a stable, positive sampling seed from an agent ID
+func agentSeed(id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	seed := int(h.Sum32() & 0x7fffffff)
+	if seed == 0 {
+		seed = 1 // zero would be omitted from the request
+	}
+	return seed
+}
+
+func (s *simulator) createNewTopic(ctx context.Context, gen Generator, agent agents.Agent) (string, error) {
+	lang := s.languageFor(agent)
+	prompt, err := s.prompts.render(promptCreateTopic, promptData{Agent: agent, Domain: s.domain, Language: languageName(lang)})
+	if err != nil {
+		return "", err
+	}
+	prompt += s.prompts.languageInstruction(promptCreateTopic, lang)
 
 	fmt.Printf("   📝 Sending prompt to Ollama: %s\n", prompt[:min(100, len(prompt))]+"...")
 
-	content, err := ollama.GenerateResponse(prompt)
+	content, debug, err := s.generateContent(ctx, gen, agent.Model, persona(agent), prompt, s.generationOptions(agent))
 	if err != nil {
-		return fmt.Errorf("generating topic: %w", err)
+		return "", fmt.Errorf("generating topic: %w", err)
+	}
+
+	title, body := splitTopic(content)
+	fmt.Printf("   ✨ Generated topic: %s\n", title[:min(100, len(title))]+"...")
+
+	if ok, reason := s.blocklist.moderate(content); !ok {
+		fmt.Printf("   🚫 Topic not saved: %s\n", reason)
+		return "", nil
 	}
 
-	fmt.Printf("   ✨ Generated topic: %s\n", content[:min(100, len(content))]+"...")
+	if s.dedupThreshold > 0 {
+		duplicate, err := community.IsDuplicateTopic(s.communityDir, title, s.dedupThreshold)
+		if err != nil {
+			return "", fmt.Errorf("checking for duplicates: %w", err)
+		}
+		if duplicate {
+			fmt.Printf("   ♻️  Suppressed: too similar to a recent topic\n")
+			return "", nil
+		}
+	}
+
+	tags, err := s.generateTags(ctx, gen, title+"\n\n"+body)
+	if err != nil {
+		fmt.Printf("   ⚠️  Tagging failed, saving without tags: %v\n", err)
+		tags = []string{}
+	}
+	fmt.Printf("   🏷️  Tags: %s\n", strings.Join(tags, ", "))
 
 	topic := community.Topic{
-		Title:     content,
-		Body:      content,
+		Title:     title,
+		Body:      body,
 		Author:    agent.ID,
 		Timestamp: time.Now().Format(time.RFC3339),
-		Tags:      []string{}, // Will be enhanced in Phase 2
+		Board:     s.pickBoard(agent),
+		Lang:      lang,
+		Tags:      tags,
 		Replies:   []community.Reply{},
+		Debug:     debug,
 	}
 
-	if err := community.SaveTopic(topic, "data/community"); err != nil {
-		return fmt.Errorf("saving topic: %w", err)
+	saved, err := community.CreateTopic(s.communityDir, topic)
+	if err != nil {
+		return "", fmt.Errorf("saving topic: %w", err)
 	}
 
+	s.session.topics.Add(1)
+	s.remember(agent, saved.Filename)
 	fmt.Printf("   💾 Topic saved successfully\n")
-	return nil
+
+	path := filepath.ToSlash(saved.Filename)
+	if s.maxTopics > 0 {
+		archived, err := community.EvictToLimit(s.communityDir, s.maxTopics)
+		if err != nil {
+			return path, fmt.Errorf("evicting topics: %w", err)
+		}
+		if archived > 0 {
+			fmt.Printf("   🗄️  Archived %d inactive topics to stay under %d\n", archived, s.maxTopics)
+		}
+	}
+	return path, nil
 }
 
-func replyToTopic(agent agents.Agent, topic community.Topic) error {
+func (s *simulator) replyToTopic(ctx context.Context, gen Generator, agent agents.Agent, topic community.Topic, parent *community.Reply) error {
 	// Build conversation context
-	context := fmt.Sprintf("Original Topic: %s\n\n%s", topic.Title, topic.Body)
+	thread := s.buildReplyContext(ctx, gen, &topic)
 
-	if len(topic.Replies) > 0 {
-		context += "\n\nPrevious Replies:\n"
-		for i, reply := range topic.Replies {
-			context += fmt.Sprintf("%d. %s: %s\n", i+1, reply.Author, reply.Content)
-		}
+	lang := s.languageFor(agent)
+	data := promptData{Agent: agent, Domain: s.domain, Language: languageName(lang), Topic: topic, Thread: thread}
+	name := promptReply
+	if parent != nil {
+		// Quote the comment in full so the model answers its point rather
+		// than the thread at large
+		name, data.Quoted, data.Quote = promptQuoteReply, *parent, quoteLines(parent.Content)
+	}
+	prompt, err := s.prompts.render(name, data)
+	if err != nil {
+		return err
 	}
+	prompt += mentionHint(agent, topic, s.agentsByID) + s.prompts.languageInstruction(name, lang)
 
-	prompt := fmt.Sprintf("You are %s, %s. Here is the ongoing discussion:\n\n%s\n\nPlease provide a thoughtful reply that adds value to this conversation. Keep your response to 1-2 sentences.", agent.Name, agent.Style, context)
+	var images [][]byte
+	if s.imagesDir != "" && topic.Image != "" {
+		img, err := loadTopicImage(s.imagesDir, topic.Image)
+		if err != nil {
+			fmt.Printf("   ⚠️  Replying without the topic image: %v\n", err)
+		} else {
+			images = append(images, img)
+			prompt += "\n\nThe topic comes with the attached image. React to what it shows as well."
+			fmt.Printf("   🖼️  Showing %s to the model\n", topic.Image)
+		}
+	}
 
 	fmt.Printf("   💬 Replying to topic with %d existing replies\n", len(topic.Replies))
 	fmt.Printf("   📝 Sending prompt to Ollama: %s\n", prompt[:min(150, len(prompt))]+"...")
 
-	content, err := ollama.GenerateResponse(prompt)
+	content, debug, err := s.generateContent(ctx, gen, agent.Model, persona(agent), prompt, s.generationOptions(agent), images...)
 	if err != nil {
 		return fmt.Errorf("generating reply: %w", err)
 	}
 
 	fmt.Printf("   ✨ Generated reply: %s\n", content[:min(100, len(content))]+"...")
 
+	if ok, reason := s.blocklist.moderate(content); !ok {
+		fmt.Printf("   🚫 Reply not saved: %s\n", reason)
+		return nil
+	}
+
 	reply := community.Reply{
 		Author:    agent.ID,
 		Content:   content,
 		Timestamp: time.Now().Format(time.RFC3339),
+		Mentions:  parseMentions(content, s.agentsByID, agent.ID),
+		Lang:      lang,
+		Debug:     debug,
+	}
+	if len(reply.Mentions) > 0 {
+		fmt.Printf("   📣 Mentions @%s\n", strings.Join(reply.Mentions, ", @"))
 	}
 
-	if err := community.AddReplyToTopic(topic.Title, reply, "data/community"); err != nil {
+	if parent != nil {
+		err = community.AddReplyToReply(s.communityDir, topic.Filename, parent.ID, reply)
+	} else {
+		err = community.AddReplyByPath(s.communityDir, topic.Filename, reply)
+	}
+	if err != nil {
 		return fmt.Errorf("adding reply: %w", err)
 	}
 
+	s.session.replies.Add(1)
+	s.remember(agent, topic.Filename)
 	fmt.Printf("   💾 Reply saved successfully\n")
 	return nil
 }
 
+// untouchedTopics filters out topics the agent recently created or replied to
+func (s *simulator) untouchedTopics(agent agents.Agent, topics []community.Topic) []community.Topic {
+	var fresh []community.Topic
+	for _, t := range topics {
+		if !s.memory.Touched(agent.ID, filepath.ToSlash(t.Filename)) {
+			fresh = append(fresh, t)
+		}
+	}
+	if skipped := len(topics) - len(fresh); skipped > 0 {
+		fmt.Printf("   🧠 Skipping %d topics %s took part in recently\n", skipped, agent.Name)
+	}
+	return fresh
+}
+
+// openTopics filters out topics that have reached the reply cap
+func (s *simulator) openTopics(topics []community.Topic) []community.Topic {
+	if s.maxReplies <= 0 {
+		return topics
+	}
+	var open []community.Topic
+	for _, t := range topics {
+		if len(t.Replies) < s.maxReplies {
+			open = append(open, t)
+		}
+	}
+	if closed := len(topics) - len(open); closed > 0 {
+		fmt.Printf("   🔒 Skipping %d topics that reached %d replies\n", closed, s.maxReplies)
+	}
+	return open
+}
+
+// repliableTopics filters out threads where the agent is on cooldown, so one
+// chatty agent doesn't answer the same thread turn after turn
+func (s *simulator) repliableTopics(agent agents.Agent, topics []community.Topic) []community.Topic {
+	var allowed []community.Topic
+	for _, t := range topics {
+		if canReply(agent, t, s.replyCooldown) {
+			allowed = append(allowed, t)
+		}
+	}
+	if waiting := len(topics) - len(allowed); waiting > 0 {
+		fmt.Printf("   ⏸️  Skipping %d topics where %s wrote one of the last %d replies\n", waiting, agent.Name, s.replyCooldown)
+	}
+	return allowed
+}
+
+// boardTopics keeps the topics on boards the agent frequents. Agents without
+// boards of their own go everywhere.
+func (s *simulator) boardTopics(agent agents.Agent, topics []community.Topic) []community.Topic {
+	if len(agent.Boards) == 0 {
+		return topics
+	}
+	mine := make(map[string]bool, len(agent.Boards))
+	for _, b := range agent.Boards {
+		mine[community.BoardName(b)] = true
+	}
+
+	var kept []community.Topic
+	for _, t := range topics {
+		if mine[community.BoardName(t.Board)] {
+			kept = append(kept, t)
+		}
+	}
+	if skipped := len(topics) - len(kept); skipped > 0 {
+		fmt.Printf("   🗂️  Skipping %d topics outside %s's boards\n", skipped, agent.Name)
+	}
+	return kept
+}
+
+// pickBoard files a new topic under one of the agent's boards, or one of the
+// community's when the agent has none. Without any boards it returns "",
+// leaving the topic on the default board.
+func (s *simulator) pickBoard(agent agents.Agent) string {
+	boards := agent.Boards
+	if len(boards) == 0 {
+		boards = s.boards
+	}
+	if len(boards) == 0 {
+		return ""
+	}
+	return community.BoardName(boards[s.rng.Intn(len(boards))])
+}
+
+// canReply reports whether agent may reply to topic: not while it wrote any
+// of the last cooldown replies. A cooldown of 0 always allows it.
+func canReply(agent agents.Agent, topic community.Topic, cooldown int) bool {
+	if cooldown <= 0 {
+		return true
+	}
+	for _, r := range topic.Replies[max(0, len(topic.Replies)-cooldown):] {
+		if r.Author == agent.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// remember records that the agent touched a topic and persists the memory
+func (s *simulator) remember(agent agents.Agent, topicPath string) {
+	s.memory.Record(agent.ID, filepath.ToSlash(topicPath))
+	if err := s.memory.Save(s.memoryPath); err != nil {
+		fmt.Printf("   ⚠️  Saving agent memory failed: %v\n", err)
+	}
+}
+
+// pickParentReply occasionally selects an earlier reply to quote and answer
+// directly, favoring recent replies that few others have answered. It
+// returns nil when the agent should reply to the topic itself.
+func (s *simulator) pickParentReply(topic community.Topic) *community.Reply {
+	if s.rng.Float64() >= s.nestedReplyProb {
+		return nil
+	}
+
+	answers := make(map[string]int)
+	for _, reply := range topic.Replies {
+		if reply.ParentID != "" {
+			answers[reply.ParentID]++
+		}
+	}
+
+	var candidates []community.Reply
+	var weights []float64
+	total := 0.0
+	for i, reply := range topic.Replies {
+		if reply.ID == "" { // replies saved before threading have no ID yet
+			continue
+		}
+		newer := len(topic.Replies) - 1 - i
+		w := quoteWeight(newer, answers[reply.ID])
+		candidates = append(candidates, reply)
+		weights = append(weights, w)
+		total += w
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	r := s.rng.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return &candidates[i]
+		}
+		r -= w
+	}
+	return &candidates[len(candidates)-1]
+}
+
+// quoteWeight weighs a reply for quoting: it halves for every two replies
+// posted after it and shrinks with each answer it already has
+func quoteWeight(newer, answers int) float64 {
+	return math.Pow(0.5, float64(newer)/2) / float64(1+answers)
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
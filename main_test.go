@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"kommunity/agents"
+	"kommunity/ollama"
+)
+
+// sentSeed runs one generation for agent and returns the seed the request
+// carried, or 0 without options
+func sentSeed(t *testing.T, s *simulator, agent agents.Agent) int {
+	t.Helper()
+	var seed int
+	gen := generatorFunc(func(ctx context.Context, req ollama.Request) (string, error) {
+		if req.Options != nil {
+			seed = req.Options.Seed
+		}
+		return "ok", nil
+	})
+	if _, err := s.complete(context.Background(), gen, "", "", "Write a post", s.generationOptions(agent)); err != nil {
+		t.Fatal(err)
+	}
+	return seed
+}
+
+func TestAgentSeedsAreStablePerAgent(t *testing.T) {
+	s := &simulator{agentSeeds: true}
+	julia := agents.Agent{ID: "julia_child", Name: "Julia Child"}
+	gordon := agents.Agent{ID: "gordon_ramsay", Name: "Gordon Ramsay"}
+
+	first := sentSeed(t, s, julia)
+	if first == 0 {
+		t.Fatal("request carried no seed")
+	}
+	for i := 0; i < 3; i++ {
+		if again := sentSeed(t, s, julia); again != first {
+			t.Errorf("turn %d sent seed %d, want %d as on the first turn", i+2, again, first)
+		}
+	}
+	if other := sentSeed(t, s, gordon); other == first {
+		t.Errorf("two agents share seed %d", other)
+	}
+
+	if seed := sentSeed(t, &simulator{}, julia); seed != 0 {
+		t.Errorf("without -agent-seeds the request carried seed %d", seed)
+	}
+}
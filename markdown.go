@@ -0,0 +1,164 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// renderMarkdown converts the Markdown subset agents tend to produce into
+// HTML: paragraphs, headings, lists, blockquotes, fenced and inline code,
+// bold, italics, and links. All input text is escaped before any markup is
+// added, so HTML in the source is shown literally rather than executed.
+func renderMarkdown(src string) template.HTML {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	var b strings.Builder
+	var para []string
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + strings.Join(para, "<br>\n") + "</p>\n")
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case headingPattern.MatchString(trimmed):
+			flush()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			b.WriteString("<blockquote>" + string(renderMarkdown(strings.Join(quote, "\n"))) + "</blockquote>\n")
+
+		case bulletPattern.MatchString(trimmed), orderedPattern.MatchString(trimmed):
+			flush()
+			pattern, tag := bulletPattern, "ul"
+			if orderedPattern.MatchString(trimmed) {
+				pattern, tag = orderedPattern, "ol"
+			}
+			b.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && pattern.MatchString(strings.TrimSpace(lines[i])); i++ {
+				item := pattern.FindStringSubmatch(strings.TrimSpace(lines[i]))[1]
+				b.WriteString("<li>" + renderInline(item) + "</li>\n")
+			}
+			i--
+			b.WriteString("</" + tag + ">\n")
+
+		default:
+			para = append(para, renderInline(trimmed))
+		}
+	}
+	flush()
+
+	return template.HTML(b.String())
+}
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*$`)
+	bulletPattern  = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+
+	linkPattern  = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldPattern  = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	starPattern  = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	underPattern = regexp.MustCompile(`(^|[^\w])_(\S(?:[^_]*?\S)?)_([^\w]|$)`)
+)
+
+// renderInline escapes text and applies inline code, links, bold, and
+// italics. Code spans are left untouched by the other rules.
+func renderInline(text string) string {
+	parts := strings.Split(text, "`")
+	var b strings.Builder
+	for i, part := range parts {
+		// Odd parts sit between backticks; an unmatched final backtick is literal
+		if i%2 == 1 && i < len(parts)-1 {
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+			continue
+		}
+		if i%2 == 1 {
+			b.WriteString("`")
+		}
+		b.WriteString(renderEmphasis(html.EscapeString(part)))
+	}
+	return b.String()
+}
+
+// renderEmphasis applies links, bold, and italics to already escaped text
+func renderEmphasis(escaped string) string {
+	escaped = linkPattern.ReplaceAllStringFunc(escaped, func(m string) string {
+		parts := linkPattern.FindStringSubmatch(m)
+		if !safeURL(html.UnescapeString(parts[2])) {
+			return m
+		}
+		return `<a href="` + parts[2] + `" rel="nofollow noopener">` + parts[1] + `</a>`
+	})
+	escaped = boldPattern.ReplaceAllString(escaped, "<strong>$1$2</strong>")
+	escaped = starPattern.ReplaceAllString(escaped, "<em>$1</em>")
+	escaped = underPattern.ReplaceAllString(escaped, "$1<em>$2</em>$3")
+	return escaped
+}
+
+// stripMarkdown reduces the same Markdown subset to plain text on one line:
+// markers such as headings, bullets, quotes, and emphasis are dropped, links
+// keep their text, and code keeps its content.
+func stripMarkdown(src string) string {
+	var words []string
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			continue
+		}
+		for strings.HasPrefix(line, ">") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, ">"))
+		}
+		for _, pattern := range []*regexp.Regexp{headingPattern, bulletPattern, orderedPattern} {
+			if m := pattern.FindStringSubmatch(line); m != nil {
+				line = m[len(m)-1]
+				break
+			}
+		}
+		line = linkPattern.ReplaceAllString(line, "$1")
+		line = boldPattern.ReplaceAllString(line, "$1$2")
+		line = starPattern.ReplaceAllString(line, "$1")
+		line = underPattern.ReplaceAllString(line, "$1$2$3")
+		line = strings.ReplaceAll(line, "`", "")
+		words = append(words, strings.Fields(line)...)
+	}
+	return strings.Join(words, " ")
+}
+
+// safeURL allows web and mail links and relative paths, rejecting schemes
+// such as javascript: that could run code.
+func safeURL(u string) bool {
+	lower := strings.ToLower(strings.TrimSpace(u))
+	for _, scheme := range []string{"http://", "https://", "mailto:"} {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+	return !strings.Contains(lower, ":") || strings.HasPrefix(lower, "/") || strings.HasPrefix(lower, "#")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kommunity/agents"
+	"kommunity/community"
+)
+
+// mentionPattern matches @agent_id tokens that start a word, so e-mail
+// addresses aren't taken for mentions
+var mentionPattern = regexp.MustCompile(`(^|[^\w@/])@(\w+)`)
+
+// parseMentions returns the known agents @mentioned in content, in order of
+// first appearance. Unknown IDs and the author's own ID are ignored.
+func parseMentions(content string, known map[string]agents.Agent, author string) []string {
+	var mentions []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		id := strings.ToLower(m[2])
+		if _, ok := known[id]; !ok || id == author || seen[id] {
+			continue
+		}
+		seen[id] = true
+		mentions = append(mentions, id)
+	}
+	return mentions
+}
+
+// mentionHint invites the agent to address another participant of the
+// thread directly, or is empty when nobody else has spoken yet
+func mentionHint(agent agents.Agent, topic community.Topic, known map[string]agents.Agent) string {
+	var others []string
+	seen := map[string]bool{agent.ID: true}
+	authors := []string{topic.Author}
+	for _, r := range topic.Replies {
+		authors = append(authors, r.Author)
+	}
+	for _, id := range authors {
+		if _, ok := known[id]; ok && !seen[id] {
+			seen[id] = true
+			others = append(others, "@"+id)
+		}
+	}
+	if len(others) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" If you want to put a question to someone in particular, mention them by ID: %s.", strings.Join(others, ", "))
+}
+
+// linkMentions turns the stored mentions in Markdown source into links to the
+// mentioned agents' author pages, leaving code spans alone
+func linkMentions(basePath, content string, mentions []string) string {
+	if len(mentions) == 0 {
+		return content
+	}
+	mentioned := make(map[string]bool, len(mentions))
+	for _, id := range mentions {
+		mentioned[id] = true
+	}
+
+	parts := strings.Split(content, "`")
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = mentionPattern.ReplaceAllStringFunc(parts[i], func(m string) string {
+			sub := mentionPattern.FindStringSubmatch(m)
+			id := strings.ToLower(sub[2])
+			if !mentioned[id] {
+				return m
+			}
+			return fmt.Sprintf("%s[@%s](%s/author/%s)", sub[1], sub[2], basePath, id)
+		})
+	}
+	return strings.Join(parts, "`")
+}
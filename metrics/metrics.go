@@ -0,0 +1,162 @@
+// Package metrics keeps process-wide counters and histograms and serves them
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Simulation and model backend metrics
+var (
+	TopicsCreated = NewCounter("kommunity_topics_created_total", "Topics created by this process.")
+	RepliesAdded  = NewCounter("kommunity_replies_added_total", "Replies added by this process.")
+
+	OllamaRequestDuration = NewHistogram("kommunity_ollama_request_duration_seconds",
+		"Time spent on Ollama generate requests, successful or not.",
+		[]float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120})
+	OllamaErrors       = NewCounter("kommunity_ollama_errors_total", "Ollama generate requests that failed.")
+	OllamaPromptTokens = NewCounter("kommunity_ollama_prompt_tokens_total", "Prompt tokens evaluated by Ollama, as reported per request.")
+	OllamaOutputTokens = NewCounter("kommunity_ollama_output_tokens_total", "Tokens generated by Ollama, as reported per request.")
+)
+
+// metric is anything that can write itself in the text format
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []metric
+)
+
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+// Counter is a monotonically increasing count
+type Counter struct {
+	metricName string
+	help       string
+	value      atomic.Uint64
+}
+
+// NewCounter registers a counter
+func NewCounter(name, help string) *Counter {
+	c := &Counter{metricName: name, help: help}
+	register(c)
+	return c
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+func (c *Counter) name() string { return c.metricName }
+
+func (c *Counter) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.metricName, c.help, c.metricName, c.metricName, c.Value())
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	metricName string
+	help       string
+	bounds     []float64
+
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative; the last one is +Inf
+	sum    float64
+	total  uint64
+}
+
+// NewHistogram registers a histogram with the given upper bucket bounds
+func NewHistogram(name, help string, bounds []float64) *Histogram {
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+	h := &Histogram{metricName: name, help: help, bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+	register(h)
+	return h
+}
+
+// Observe records one value
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.sum += v
+	h.total++
+}
+
+// ObserveSince records the seconds elapsed since start
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+func (h *Histogram) name() string { return h.metricName }
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum, total := h.sum, h.total
+	h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.metricName, h.help, h.metricName)
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", h.metricName, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.metricName, total)
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", h.metricName, formatFloat(sum), h.metricName, total)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// WriteText writes every registered metric, sorted by name
+func WriteText(w io.Writer) {
+	registryMu.Lock()
+	metrics := append([]metric(nil), registry...)
+	registryMu.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name() < metrics[j].name() })
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the metrics for a Prometheus scrape
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(w)
+	})
+}
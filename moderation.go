@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// blocklist rejects generated posts containing banned terms. Terms match
+// whole words only, ignoring case, so "ass" doesn't block "class".
+type blocklist struct {
+	pattern *regexp.Regexp
+}
+
+// loadBlocklist reads one banned term or phrase per line from path, skipping
+// blank lines and # comments. A missing file disables moderation.
+func loadBlocklist(path string) (*blocklist, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening blocklist: %w", err)
+	}
+	defer file.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		term := strings.TrimSpace(scanner.Text())
+		if term == "" || strings.HasPrefix(term, "#") {
+			continue
+		}
+		// Any run of spaces in a phrase matches any run of whitespace
+		terms = append(terms, strings.Join(quoteFields(term), `\s+`))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading blocklist: %w", err)
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	// Go's \b only knows ASCII, so word boundaries are spelled out to keep
+	// accented words whole
+	pattern, err := regexp.Compile(`(?i)(?:^|[^\pL\pN_])(` + strings.Join(terms, "|") + `)(?:[^\pL\pN_]|$)`)
+	if err != nil {
+		return nil, fmt.Errorf("compiling blocklist: %w", err)
+	}
+	return &blocklist{pattern: pattern}, nil
+}
+
+// quoteFields splits a phrase into regexp-quoted words
+func quoteFields(phrase string) []string {
+	fields := strings.Fields(phrase)
+	for i, f := range fields {
+		fields[i] = regexp.QuoteMeta(f)
+	}
+	return fields
+}
+
+// moderate reports whether content may be saved, and if not, why. A nil
+// blocklist allows everything.
+func (b *blocklist) moderate(content string) (bool, string) {
+	if b == nil {
+		return true, ""
+	}
+	if m := b.pattern.FindStringSubmatch(content); m != nil {
+		return false, fmt.Sprintf("contains blocked term %q", m[1])
+	}
+	return true, ""
+}
@@ -2,82 +2,672 @@ package ollama
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"time"
+
+	"kommunity/metrics"
 )
 
 // Request represents a request to Ollama API
 type Request struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model   string   `json:"model"`
+	Prompt  string   `json:"prompt"`
+	System  string   `json:"system,omitempty"`
+	Images  []string `json:"images,omitempty"` // base64-encoded, for multimodal models such as llava
+	Stream  bool     `json:"stream"`
+	Options *Options `json:"options,omitempty"`
+}
+
+// Options carries model sampling parameters, sent as the request's
+// "options" field. Unset values are omitted so Ollama's defaults apply; the
+// float parameters are pointers so an explicit 0 is still sent.
+type Options struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	TopK        int      `json:"top_k,omitempty"`
+	Seed        int      `json:"seed,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
 }
 
-// Response represents a response from Ollama API
+// Response represents a response from Ollama API. When streaming, each
+// chunk is a Response carrying a fragment of the text, and only the final
+// one carries the counts and durations (in nanoseconds).
 type Response struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
+	Response           string `json:"response"`
+	Done               bool   `json:"done"`
+	Error              string `json:"error,omitempty"`
+	TotalDuration      int64  `json:"total_duration,omitempty"`
+	LoadDuration       int64  `json:"load_duration,omitempty"`
+	PromptEvalCount    int    `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64  `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int    `json:"eval_count,omitempty"`
+	EvalDuration       int64  `json:"eval_duration,omitempty"`
+}
+
+// Stats are the token counts and timings Ollama reports for a generation.
+// Fields are zero when the server leaves them out, e.g. PromptTokens when
+// the prompt was already cached.
+type Stats struct {
+	PromptTokens       int
+	OutputTokens       int
+	TotalDuration      time.Duration // everything, model loading included
+	LoadDuration       time.Duration
+	PromptEvalDuration time.Duration
+	EvalDuration       time.Duration // generating OutputTokens
+}
+
+// Stats returns the counts and timings of a final response
+func (r Response) Stats() Stats {
+	return Stats{
+		PromptTokens:       r.PromptEvalCount,
+		OutputTokens:       r.EvalCount,
+		TotalDuration:      time.Duration(r.TotalDuration),
+		LoadDuration:       time.Duration(r.LoadDuration),
+		PromptEvalDuration: time.Duration(r.PromptEvalDuration),
+		EvalDuration:       time.Duration(r.EvalDuration),
+	}
+}
+
+// TokensPerSecond is the generation speed, or 0 when it wasn't reported
+func (s Stats) TokensPerSecond() float64 {
+	if s.EvalDuration <= 0 {
+		return 0
+	}
+	return float64(s.OutputTokens) / s.EvalDuration.Seconds()
+}
+
+// DefaultModel is used until SetDefaultModel picks another one. Smaller
+// models such as "phi3:mini" work well on laptops.
+const DefaultModel = "llama3.1:8b"
+
+var (
+	defaultModelMu sync.RWMutex
+	defaultModel   = DefaultModel
+)
+
+// SetDefaultModel changes the model used by GenerateResponse and the other
+// helpers that don't take an explicit model.
+func SetDefaultModel(model string) {
+	defaultModelMu.Lock()
+	defer defaultModelMu.Unlock()
+	defaultModel = model
+}
+
+// GetDefaultModel returns the model used when none is given explicitly
+func GetDefaultModel() string {
+	defaultModelMu.RLock()
+	defer defaultModelMu.RUnlock()
+	return defaultModel
+}
+
+// DefaultBaseURL is the address of a local Ollama server
+const DefaultBaseURL = "http://localhost:11434"
+
+// Retry defaults used by NewClient. Ollama commonly refuses connections or
+// answers 500 while it is still loading a model.
+const (
+	DefaultMaxAttempts = 3
+	DefaultRetryDelay  = 500 * time.Millisecond
+	DefaultTimeout     = 5 * time.Minute
+)
+
+// transport is shared by every client so connections to Ollama are kept
+// alive and reused across requests. Slow generations are bounded by the
+// caller's context, or Client.Timeout, rather than by a response header
+// timeout.
+var transport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   16, // enough for concurrent agents on one server
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// Client talks to an Ollama server at BaseURL. Generate requests that fail
+// with a network error or a 5xx status are retried up to MaxAttempts times,
+// waiting RetryDelay before the first retry and doubling it after each one.
+// When Limiter is set, every attempt waits its turn first.
+//
+// Timeout bounds each HTTP request, reading the response included, so a hung
+// connection can't block a caller whose context has no deadline; 0 disables
+// it. A context deadline that runs out first is still reported as
+// context.DeadlineExceeded. Set it before the client's first request.
+type Client struct {
+	BaseURL     string
+	MaxAttempts int
+	RetryDelay  time.Duration
+	Limiter     *Limiter
+	Timeout     time.Duration
+
+	// MaxConcurrent caps how many generate calls may be in flight at once,
+	// from any caller; 0 is unlimited. It must be set before the first call.
+	MaxConcurrent int
+
+	httpOnce sync.Once
+	http     *http.Client
+
+	slotsOnce sync.Once
+	slots     chan struct{} // semaphore sized by MaxConcurrent; nil is unlimited
+}
+
+// NewClient returns a client for the Ollama server at baseURL. An empty
+// baseURL selects DefaultBaseURL; a bare "host:port" is treated as http.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:     normalizeBaseURL(baseURL),
+		MaxAttempts: DefaultMaxAttempts,
+		RetryDelay:  DefaultRetryDelay,
+		Timeout:     DefaultTimeout,
+	}
+}
+
+// httpClient returns the client's HTTP client, created on first use
+func (c *Client) httpClient() *http.Client {
+	c.httpOnce.Do(func() {
+		c.http = &http.Client{Transport: transport, Timeout: c.Timeout}
+	})
+	return c.http
+}
+
+// acquire waits for one of the MaxConcurrent generate slots and returns the
+// function that gives it back, or ctx's error if ctx is done first
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	c.slotsOnce.Do(func() {
+		if c.MaxConcurrent > 0 {
+			c.slots = make(chan struct{}, c.MaxConcurrent)
+		}
+	})
+	if c.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return func() { <-c.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// releasingBody gives back a generate slot when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// defaultClient backs the package-level helpers and honors OLLAMA_HOST
+var defaultClient = NewClient(os.Getenv("OLLAMA_HOST"))
+
+// SetRateLimit caps the package-level helpers at perSecond generate requests
+// per second, allowing bursts of up to burst; 0 removes the limit. Call it
+// before generating.
+func SetRateLimit(perSecond float64, burst int) {
+	defaultClient.Limiter = NewLimiter(perSecond, burst)
+}
+
+// SetTimeout caps each HTTP request the package-level helpers make; 0
+// removes the cap. Call it before the first request.
+func SetTimeout(d time.Duration) {
+	defaultClient.Timeout = d
+}
+
+// SetMaxConcurrent caps the generate calls the package-level helpers have in
+// flight at once; 0 is unlimited. Call it before the first request.
+func SetMaxConcurrent(n int) {
+	defaultClient.MaxConcurrent = n
 }
 
 // GenerateResponse generates a response using Ollama
 func GenerateResponse(prompt string) (string, error) {
-	req := Request{
-		Model: "llama3.1:8b", //Using llama3-groq-tool-use:8b as it's available and good for conversational AI
-		// Model: "llama3-groq-tool-use:8b", //Using llama3-groq-tool-use:8b as it's available and good for conversational AI
-		// Model:  "phi3:mini", // Using phi3:mini as it's available and good for conversational AI
-		Prompt: prompt,
-		Stream: false,
+	return defaultClient.GenerateResponse(prompt)
+}
+
+// GenerateDetailed generates a response using Ollama, along with its token
+// counts and timings
+func GenerateDetailed(prompt string) (string, Stats, error) {
+	return defaultClient.GenerateDetailed(prompt)
+}
+
+// GenerateWithModel generates a response using the given model
+func GenerateWithModel(model, prompt string) (string, error) {
+	return defaultClient.GenerateWithModel(model, prompt)
+}
+
+// GenerateWithOptions generates a response using the given sampling options
+func GenerateWithOptions(prompt string, opts Options) (string, error) {
+	return defaultClient.GenerateWithOptions(prompt, opts)
+}
+
+// GenerateWithImages generates a response using the default model, showing
+// it the given images (raw PNG or JPEG bytes). The model must be multimodal.
+func GenerateWithImages(prompt string, images [][]byte) (string, error) {
+	return defaultClient.GenerateWithImages(prompt, images)
+}
+
+// GenerateWithSystem generates a response with a system message steering the
+// model, such as a persona description
+func GenerateWithSystem(system, prompt string) (string, error) {
+	return defaultClient.GenerateWithSystem(system, prompt)
+}
+
+// GenerateResponseContext generates a response, giving up when ctx is done
+func GenerateResponseContext(ctx context.Context, prompt string) (string, error) {
+	return defaultClient.GenerateResponseContext(ctx, prompt)
+}
+
+// GenerateRequest sends a fully specified request
+func GenerateRequest(ctx context.Context, req Request) (string, error) {
+	return defaultClient.GenerateRequest(ctx, req)
+}
+
+// GenerateRequestDetailed sends a fully specified request and also returns
+// the generation's token counts and timings
+func GenerateRequestDetailed(ctx context.Context, req Request) (string, Stats, error) {
+	return defaultClient.GenerateRequestDetailed(ctx, req)
+}
+
+// GenerateStream generates a response with the default model, calling onToken
+// with each fragment as it is produced
+func GenerateStream(prompt string, onToken func(string) error) error {
+	return defaultClient.GenerateStream(prompt, onToken)
+}
+
+// StreamRequest sends a fully specified request with streaming enabled
+func StreamRequest(ctx context.Context, req Request, onToken func(string) error) (string, error) {
+	return defaultClient.StreamRequest(ctx, req, onToken)
+}
+
+// ListModels returns the names of the models installed on the Ollama server
+func ListModels() ([]string, error) {
+	return defaultClient.ListModels()
+}
+
+// IsOllamaRunning checks if Ollama is running and accessible
+func IsOllamaRunning() bool {
+	return defaultClient.IsRunning()
+}
+
+// IsOllamaRunningContext is IsOllamaRunning, giving up when ctx is done
+func IsOllamaRunningContext(ctx context.Context) bool {
+	return defaultClient.IsRunningContext(ctx)
+}
+
+// GenerateResponse generates a response using the default model
+func (c *Client) GenerateResponse(prompt string) (string, error) {
+	return c.GenerateResponseContext(context.Background(), prompt)
+}
+
+// GenerateDetailed generates a response using the default model, along with
+// the token counts and timings Ollama reported
+func (c *Client) GenerateDetailed(prompt string) (string, Stats, error) {
+	return c.GenerateRequestDetailed(context.Background(), newRequest(GetDefaultModel(), prompt))
+}
+
+// GenerateResponseContext generates a response using the default model,
+// giving up when ctx is done
+func (c *Client) GenerateResponseContext(ctx context.Context, prompt string) (string, error) {
+	return c.GenerateRequest(ctx, newRequest(GetDefaultModel(), prompt))
+}
+
+// GenerateStream generates a response with the default model, calling
+// onToken with each fragment as it is produced
+func (c *Client) GenerateStream(prompt string, onToken func(string) error) error {
+	_, err := c.StreamRequest(context.Background(), newRequest(GetDefaultModel(), prompt), onToken)
+	return err
+}
+
+// GenerateWithModel generates a response using the given model
+func (c *Client) GenerateWithModel(model, prompt string) (string, error) {
+	if strings.TrimSpace(model) == "" {
+		return "", fmt.Errorf("ollama model name is empty")
 	}
+	return c.GenerateRequest(context.Background(), newRequest(model, prompt))
+}
 
-	start := time.Now()
-	log.Printf("ollama: generate request started model=%s at=%s", req.Model, start.Format(time.RFC3339Nano))
+// GenerateWithOptions generates a response using the given sampling options
+func (c *Client) GenerateWithOptions(prompt string, opts Options) (string, error) {
+	req := newRequest(GetDefaultModel(), prompt)
+	if opts != (Options{}) {
+		req.Options = &opts
+	}
+	return c.GenerateRequest(context.Background(), req)
+}
 
-	jsonData, err := json.Marshal(req)
+// GenerateWithSystem generates a response using the default model, with
+// system sent as the system message and prompt as the user prompt
+func (c *Client) GenerateWithSystem(system, prompt string) (string, error) {
+	req := newRequest(GetDefaultModel(), prompt)
+	req.System = system
+	return c.GenerateRequest(context.Background(), req)
+}
+
+// GenerateWithImages generates a response using the default model, sending
+// images base64-encoded alongside prompt
+func (c *Client) GenerateWithImages(prompt string, images [][]byte) (string, error) {
+	req := newRequest(GetDefaultModel(), prompt)
+	req.Images = EncodeImages(images)
+	return c.GenerateRequest(context.Background(), req)
+}
+
+// EncodeImages base64-encodes raw images for Request.Images
+func EncodeImages(images [][]byte) []string {
+	if len(images) == 0 {
+		return nil
+	}
+	encoded := make([]string, len(images))
+	for i, img := range images {
+		encoded[i] = base64.StdEncoding.EncodeToString(img)
+	}
+	return encoded
+}
+
+// IsRunning checks if the Ollama server is running and accessible
+func (c *Client) IsRunning() bool {
+	return c.IsRunningContext(context.Background())
+}
+
+// IsRunningContext is IsRunning, reporting false if ctx is done before the
+// server answers
+func (c *Client) IsRunningContext(ctx context.Context) bool {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api/tags", nil)
 	if err != nil {
-		log.Printf("ollama: generate request failed model=%s err=%v elapsed=%s", req.Model, err, time.Since(start))
-		return "", fmt.Errorf("marshaling request: %w", err)
+		return false
+	}
+	resp, err := c.httpClient().Do(httpReq)
+	if err != nil {
+		return false
 	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// tagsResponse is the body of GET /api/tags
+type tagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
 
-	resp, err := http.Post("http://localhost:11434/api/generate", "application/json", bytes.NewBuffer(jsonData))
+// ListModels returns the names of the models installed on the server, as
+// reported by /api/tags (e.g. "llama3.1:8b")
+func (c *Client) ListModels() ([]string, error) {
+	resp, err := c.httpClient().Get(c.BaseURL + "/api/tags")
 	if err != nil {
-		log.Printf("ollama: generate request failed model=%s err=%v elapsed=%s", req.Model, err, time.Since(start))
-		return "", fmt.Errorf("making HTTP request: %w", err)
+		return nil, fmt.Errorf("making HTTP request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		err := fmt.Errorf("ollama API error (status %d): %s", resp.StatusCode, string(body))
-		log.Printf("ollama: generate request failed model=%s err=%v elapsed=%s", req.Model, err, time.Since(start))
-		return "", err
+		return nil, fmt.Errorf("ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var tags tagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("decoding tags response: %w", err)
+	}
+
+	names := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// HasModel reports whether model is among names. A model given without a
+// tag matches its ":latest" variant, as it does for the ollama CLI.
+func HasModel(names []string, model string) bool {
+	if !strings.Contains(model, ":") {
+		model += ":latest"
 	}
+	for _, name := range names {
+		if name == model {
+			return true
+		}
+	}
+	return false
+}
+
+func newRequest(model, prompt string) Request {
+	return Request{
+		Model:  model,
+		Prompt: prompt,
+		Stream: false,
+	}
+}
+
+// GenerateRequest sends a fully specified request. An empty Model selects the
+// default model. If ctx expires first, the returned error wraps ctx.Err() so
+// callers can detect context.DeadlineExceeded.
+func (c *Client) GenerateRequest(ctx context.Context, req Request) (string, error) {
+	text, _, err := c.GenerateRequestDetailed(ctx, req)
+	return text, err
+}
+
+// GenerateRequestDetailed is GenerateRequest, also returning the token
+// counts and timings Ollama reported
+func (c *Client) GenerateRequestDetailed(ctx context.Context, req Request) (string, Stats, error) {
+	req.Stream = false
+
+	start := time.Now()
+	resp, err := c.post(ctx, &req, start)
+	if err != nil {
+		return "", Stats{}, err
+	}
+	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("ollama: generate request failed model=%s err=%v elapsed=%s", req.Model, err, time.Since(start))
-		return "", fmt.Errorf("reading response body: %w", err)
+		return "", Stats{}, logFailure(req.Model, start, readError(ctx, start, err))
 	}
 
 	var ollamaResp Response
 	if err := json.Unmarshal(body, &ollamaResp); err != nil {
-		log.Printf("ollama: generate request failed model=%s err=%v elapsed=%s", req.Model, err, time.Since(start))
-		return "", fmt.Errorf("unmarshaling response: %w", err)
+		return "", Stats{}, logFailure(req.Model, start, fmt.Errorf("unmarshaling response: %w", err))
 	}
 
-	log.Printf("ollama: generate request completed model=%s elapsed=%s", req.Model, time.Since(start))
-	return ollamaResp.Response, nil
+	stats := ollamaResp.Stats()
+	logSuccess("generate", req.Model, start, stats)
+	return ollamaResp.Response, stats, nil
 }
 
-// IsOllamaRunning checks if Ollama is running and accessible
-func IsOllamaRunning() bool {
-	resp, err := http.Get("http://localhost:11434/api/tags")
+// StreamRequest sends req with streaming enabled and calls onToken with each
+// response fragment as it arrives. It returns the full generated text once
+// Ollama reports the generation is done. An error from onToken stops the
+// stream and is returned wrapped.
+func (c *Client) StreamRequest(ctx context.Context, req Request, onToken func(string) error) (string, error) {
+	req.Stream = true
+
+	start := time.Now()
+	resp, err := c.post(ctx, &req, start)
 	if err != nil {
-		return false
+		return "", err
 	}
 	defer resp.Body.Close()
-	return resp.StatusCode == http.StatusOK
+
+	// Each chunk is a JSON object on its own line; the decoder handles objects
+	// split across reads.
+	decoder := json.NewDecoder(resp.Body)
+	var full strings.Builder
+	for {
+		var chunk Response
+		if err := decoder.Decode(&chunk); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("stream ended before generation was done")
+			}
+			return full.String(), logFailure(req.Model, start, readError(ctx, start, err))
+		}
+		if chunk.Error != "" {
+			return full.String(), logFailure(req.Model, start, fmt.Errorf("ollama stream error: %s", chunk.Error))
+		}
+
+		if chunk.Response != "" {
+			full.WriteString(chunk.Response)
+			if err := onToken(chunk.Response); err != nil {
+				return full.String(), logFailure(req.Model, start, fmt.Errorf("handling streamed token: %w", err))
+			}
+		}
+		if chunk.Done {
+			logSuccess("stream", req.Model, start, chunk.Stats())
+			break
+		}
+	}
+
+	return full.String(), nil
+}
+
+// post validates req, sends it to the generate endpoint, and returns the
+// response once Ollama has answered with 200 OK. Transient failures are
+// retried with exponential backoff. Each attempt holds a MaxConcurrent slot,
+// and a successful one keeps it until the response body is closed.
+func (c *Client) post(ctx context.Context, req *Request, start time.Time) (*http.Response, error) {
+	if req.Model == "" {
+		req.Model = GetDefaultModel()
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return nil, fmt.Errorf("ollama model name is empty")
+	}
+
+	log.Printf("ollama: generate request started model=%s stream=%t at=%s", req.Model, req.Stream, start.Format(time.RFC3339Nano))
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, logFailure(req.Model, start, fmt.Errorf("marshaling request: %w", err))
+	}
+
+	attempts := max(1, c.MaxAttempts)
+	delay := c.RetryDelay
+	for attempt := 1; ; attempt++ {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, logFailure(req.Model, start, abortedError(start, err))
+		}
+		release, err := c.acquire(ctx)
+		if err != nil {
+			return nil, logFailure(req.Model, start, abortedError(start, err))
+		}
+		resp, retry, err := c.send(ctx, jsonData, start)
+		if err == nil {
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+			return resp, nil
+		}
+		release()
+		if !retry || attempt == attempts {
+			if attempt > 1 {
+				err = fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+			return nil, logFailure(req.Model, start, err)
+		}
+
+		log.Printf("ollama: attempt %d/%d failed model=%s err=%v, retrying in %s", attempt, attempts, req.Model, err, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, logFailure(req.Model, start, abortedError(start, ctx.Err()))
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// send makes a single generate call. It reports whether a failure is worth
+// retrying: network errors and 5xx responses are, everything else is not.
+func (c *Client) send(ctx context.Context, body []byte, start time.Time) (*http.Response, bool, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("building HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient().Do(httpReq)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, false, abortedError(start, ctxErr)
+		}
+		// A request that timed out, connecting or generating, would most likely
+		// time out again
+		var netErr net.Error
+		retry := !errors.As(err, &netErr) || !netErr.Timeout()
+		return nil, retry, fmt.Errorf("making HTTP request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, resp.StatusCode >= 500, fmt.Errorf("ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return resp, false, nil
+}
+
+// logSuccess records a completed request of the given kind in the metrics
+// and the log, with its token counts and speed
+func logSuccess(kind, model string, start time.Time, stats Stats) {
+	metrics.OllamaRequestDuration.ObserveSince(start)
+	metrics.OllamaPromptTokens.Add(uint64(stats.PromptTokens))
+	metrics.OllamaOutputTokens.Add(uint64(stats.OutputTokens))
+	log.Printf("ollama: %s request completed model=%s elapsed=%s prompt_tokens=%d output_tokens=%d tokens_per_sec=%.1f",
+		kind, model, time.Since(start), stats.PromptTokens, stats.OutputTokens, stats.TokensPerSecond())
+}
+
+func logFailure(model string, start time.Time, err error) error {
+	metrics.OllamaRequestDuration.ObserveSince(start)
+	metrics.OllamaErrors.Inc()
+	log.Printf("ollama: generate request failed model=%s err=%v elapsed=%s", model, err, time.Since(start))
+	return err
+}
+
+// readError explains a failure while reading the response body, preferring
+// the context's error when the request was canceled or timed out.
+func readError(ctx context.Context, start time.Time, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return abortedError(start, ctxErr)
+	}
+	return fmt.Errorf("reading response body: %w", err)
+}
+
+func abortedError(start time.Time, ctxErr error) error {
+	return fmt.Errorf("ollama request aborted after %s: %w", time.Since(start).Round(time.Millisecond), ctxErr)
+}
+
+// normalizeBaseURL accepts the forms OLLAMA_HOST commonly takes ("", "host",
+// "host:port", "http://host:port/") and returns a URL without a trailing slash.
+func normalizeBaseURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return DefaultBaseURL
+	}
+	if !strings.Contains(raw, "://") {
+		// Like the ollama CLI, a bare host means plain http on the default port
+		raw = "http://" + raw
+		if u, err := url.Parse(raw); err == nil && u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "11434")
+			raw = u.String()
+		}
+	}
+	return strings.TrimRight(raw, "/")
 }
@@ -0,0 +1,426 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOptionsSendExplicitZeroTemperature(t *testing.T) {
+	zero := 0.0
+	data, err := json.Marshal(Request{Model: "m", Prompt: "p", Options: &Options{Temperature: &zero}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"options":{"temperature":0}`) {
+		t.Errorf("request %s does not carry the zero temperature", data)
+	}
+
+	if data, err = json.Marshal(Options{}); err != nil || string(data) != "{}" {
+		t.Errorf("unset options marshal to %s (%v), want {}", data, err)
+	}
+}
+
+func TestRetryDelayHoldsNoConcurrencySlot(t *testing.T) {
+	failed := make(chan struct{})
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req Request
+		json.Unmarshal(body, &req)
+		if req.Prompt == "slow" {
+			first := false
+			once.Do(func() { first = true })
+			if first {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				close(failed)
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(Response{Response: "ok " + req.Prompt, Done: true})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.MaxConcurrent = 1
+	client.RetryDelay = 500 * time.Millisecond
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := client.GenerateRequest(context.Background(), Request{Model: "m", Prompt: "slow"})
+		slow <- err
+	}()
+	<-failed
+
+	// The only slot is free while the slow call waits to retry
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	text, err := client.GenerateRequest(ctx, Request{Model: "m", Prompt: "fast"})
+	if err != nil {
+		t.Fatalf("call during another's retry delay failed: %v", err)
+	}
+	if text != "ok fast" {
+		t.Errorf("got %q, want %q", text, "ok fast")
+	}
+
+	if err := <-slow; err != nil {
+		t.Errorf("retried call failed: %v", err)
+	}
+}
+
+func TestTimeoutBoundsRequestsWithoutDeadline(t *testing.T) {
+	if got := NewClient("").Timeout; got != DefaultTimeout {
+		t.Errorf("NewClient Timeout = %s, want %s", got, DefaultTimeout)
+	}
+
+	stall := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stall
+	}))
+	defer server.Close()
+	defer close(stall)
+
+	client := NewClient(server.URL)
+	client.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	if _, err := client.GenerateRequest(context.Background(), Request{Model: "m", Prompt: "p"}); err == nil {
+		t.Fatal("request to a stalled server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request to a stalled server took %s", elapsed)
+	}
+
+	// A context deadline running out first is still reported as such
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client = NewClient(server.URL)
+	if _, err := client.GenerateRequest(ctx, Request{Model: "m", Prompt: "p"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestNormalizeBaseURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"":                         DefaultBaseURL,
+		"  ":                       DefaultBaseURL,
+		"gpu-box":                  "http://gpu-box:11434",
+		"gpu-box:8080":             "http://gpu-box:8080",
+		"10.0.0.5":                 "http://10.0.0.5:11434",
+		"http://gpu-box:11434/":    "http://gpu-box:11434",
+		"https://ollama.example/":  "https://ollama.example",
+		" http://localhost:11434 ": "http://localhost:11434",
+	} {
+		if got := normalizeBaseURL(raw); got != want {
+			t.Errorf("normalizeBaseURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestNewClientUsesBaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			t.Errorf("request to %s, want /api/generate", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(Response{Response: "hi", Done: true})
+	}))
+	defer server.Close()
+
+	// A trailing slash must not produce "//api/generate"
+	text, err := NewClient(server.URL+"/").GenerateRequest(context.Background(), Request{Model: "m", Prompt: "p"})
+	if err != nil || text != "hi" {
+		t.Errorf("GenerateRequest = %q, %v; want %q", text, err, "hi")
+	}
+}
+
+func TestStreamRequestConcatenatesTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			t.Error("request was not sent with stream enabled")
+		}
+		enc := json.NewEncoder(w)
+		for _, token := range []string{"Butter", " makes", " it", " better."} {
+			enc.Encode(Response{Response: token})
+			w.(http.Flusher).Flush()
+		}
+		enc.Encode(Response{Done: true, EvalCount: 4})
+	}))
+	defer server.Close()
+
+	var tokens []string
+	text, err := NewClient(server.URL).StreamRequest(context.Background(), Request{Model: "m", Prompt: "p"}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Butter makes it better." {
+		t.Errorf("text = %q", text)
+	}
+	if len(tokens) != 4 {
+		t.Errorf("onToken called %d times, want 4", len(tokens))
+	}
+}
+
+func TestStreamRequestReportsIncompleteStreams(t *testing.T) {
+	for name, chunks := range map[string][]Response{
+		"cut off":      {{Response: "Half"}},
+		"stream error": {{Response: "Half"}, {Error: "model crashed"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, chunk := range chunks {
+					json.NewEncoder(w).Encode(chunk)
+				}
+			}))
+			defer server.Close()
+
+			text, err := NewClient(server.URL).StreamRequest(context.Background(), Request{Model: "m", Prompt: "p"}, func(string) error { return nil })
+			if err == nil {
+				t.Fatal("incomplete stream reported no error")
+			}
+			if text != "Half" {
+				t.Errorf("partial text = %q, want %q", text, "Half")
+			}
+		})
+	}
+}
+
+func TestStreamRequestStopsOnTokenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		enc.Encode(Response{Response: "one"})
+		enc.Encode(Response{Response: "two"})
+		enc.Encode(Response{Done: true})
+	}))
+	defer server.Close()
+
+	stop := errors.New("reader left")
+	_, err := NewClient(server.URL).StreamRequest(context.Background(), Request{Model: "m", Prompt: "p"}, func(string) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want it to wrap the onToken error", err)
+	}
+}
+
+func TestRetriesTransientFailuresWithBackoff(t *testing.T) {
+	var attempts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = append(attempts, time.Now())
+		if len(attempts) < 3 {
+			http.Error(w, "loading model", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(Response{Response: "ready", Done: true})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.RetryDelay = 50 * time.Millisecond
+	text, err := client.GenerateRequest(context.Background(), Request{Model: "m", Prompt: "p"})
+	if err != nil || text != "ready" {
+		t.Fatalf("GenerateRequest = %q, %v; want success on the third attempt", text, err)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("made %d attempts, want 3", len(attempts))
+	}
+	// The wait doubles: 50ms, then 100ms
+	if first, second := attempts[1].Sub(attempts[0]), attempts[2].Sub(attempts[1]); first < 50*time.Millisecond || second < 100*time.Millisecond {
+		t.Errorf("waited %s then %s between attempts, want at least 50ms then 100ms", first, second)
+	}
+}
+
+func TestRetriesGiveUpAfterMaxAttempts(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status       int
+		wantAttempts int
+	}{
+		"server error": {http.StatusBadGateway, 3},
+		"client error": {http.StatusNotFound, 1}, // e.g. an unknown model, not worth retrying
+	} {
+		t.Run(name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				http.Error(w, "nope", tc.status)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			client.RetryDelay = time.Millisecond
+			if _, err := client.GenerateRequest(context.Background(), Request{Model: "m", Prompt: "p"}); err == nil {
+				t.Fatal("GenerateRequest succeeded")
+			}
+			if n := int(attempts.Load()); n != tc.wantAttempts {
+				t.Errorf("made %d attempts, want %d", n, tc.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryBackoffStopsOnCancel(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "loading model", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.RetryDelay = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GenerateRequest(ctx, Request{Model: "m", Prompt: "p"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %s, want it to stop waiting when ctx ends", elapsed)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("made %d attempts, want 1", n)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"models": [{"name": "llama3.1:8b"}, {"name": "phi3:latest"}]}`))
+	}))
+	defer server.Close()
+
+	models, err := NewClient(server.URL).ListModels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || models[0] != "llama3.1:8b" || models[1] != "phi3:latest" {
+		t.Fatalf("ListModels = %v", models)
+	}
+
+	for model, want := range map[string]bool{
+		"llama3.1:8b":  true,
+		"phi3":         true, // untagged means :latest
+		"phi3:mini":    false,
+		"llama3.1":     false,
+		"mistral:7b":   false,
+		"llama3.1:70b": false,
+	} {
+		if got := HasModel(models, model); got != want {
+			t.Errorf("HasModel(%q) = %t, want %t", model, got, want)
+		}
+	}
+}
+
+func TestListModelsReportsServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(server.URL).ListModels(); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("err = %v, want the status reported", err)
+	}
+}
+
+func TestGenerateRequestDetailedReportsStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Response{
+			Response:           "done",
+			Done:               true,
+			PromptEvalCount:    12,
+			EvalCount:          40,
+			TotalDuration:      int64(3 * time.Second),
+			LoadDuration:       int64(time.Second),
+			PromptEvalDuration: int64(200 * time.Millisecond),
+			EvalDuration:       int64(2 * time.Second),
+		})
+	}))
+	defer server.Close()
+
+	text, stats, err := NewClient(server.URL).GenerateRequestDetailed(context.Background(), Request{Model: "m", Prompt: "p"})
+	if err != nil || text != "done" {
+		t.Fatalf("GenerateRequestDetailed = %q, %v", text, err)
+	}
+	want := Stats{
+		PromptTokens:       12,
+		OutputTokens:       40,
+		TotalDuration:      3 * time.Second,
+		LoadDuration:       time.Second,
+		PromptEvalDuration: 200 * time.Millisecond,
+		EvalDuration:       2 * time.Second,
+	}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+	if tps := stats.TokensPerSecond(); tps != 20 {
+		t.Errorf("TokensPerSecond = %g, want 20", tps)
+	}
+	if tps := (Stats{OutputTokens: 5}).TokensPerSecond(); tps != 0 {
+		t.Errorf("TokensPerSecond without a duration = %g, want 0", tps)
+	}
+}
+
+func TestConcurrencySlotHeldUntilBodyIsClosed(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		json.NewDecoder(r.Body).Decode(&req)
+		enc := json.NewEncoder(w)
+		if req.Stream {
+			enc.Encode(Response{Response: "first"})
+			w.(http.Flusher).Flush()
+			<-release
+		}
+		enc.Encode(Response{Response: " last", Done: true})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.MaxConcurrent = 1
+
+	streaming := make(chan struct{})
+	streamDone := make(chan error, 1)
+	go func() {
+		var once sync.Once
+		_, err := client.StreamRequest(context.Background(), Request{Model: "m", Prompt: "p"}, func(string) error {
+			once.Do(func() { close(streaming) })
+			return nil
+		})
+		streamDone <- err
+	}()
+	<-streaming
+
+	// The stream's response is still being read, so its slot is taken
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.GenerateRequest(ctx, Request{Model: "m", Prompt: "p"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second call got %v while the only slot was taken, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := <-streamDone; err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := client.GenerateRequest(ctx, Request{Model: "m", Prompt: "p"}); err != nil {
+		t.Fatalf("call after the stream finished: %v", err)
+	}
+	if held := len(client.slots); held != 0 {
+		t.Errorf("%d slots still held after every response was read", held)
+	}
+}
@@ -0,0 +1,65 @@
+package ollama
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces requests out to a steady rate while allowing a short burst.
+// A nil Limiter never waits.
+type Limiter struct {
+	interval time.Duration // time earned back per request
+	burst    int
+
+	mu   sync.Mutex
+	next time.Time // when the bucket would be full again if nothing else is sent
+}
+
+// NewLimiter allows perSecond requests per second on average and up to burst
+// at once. It returns nil, meaning unlimited, when perSecond is not positive.
+func NewLimiter(perSecond float64, burst int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &Limiter{
+		interval: time.Duration(float64(time.Second) / perSecond),
+		burst:    max(1, burst),
+	}
+}
+
+// Wait blocks until a request may be sent, or returns ctx's error if it is
+// done first
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	// Each request pushes next back by one interval; up to burst requests
+	// may be outstanding before callers have to wait
+	delay := l.next.Add(-time.Duration(l.burst-1) * l.interval).Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// Give the reservation back so an abandoned wait doesn't slow the
+		// requests that come after it
+		l.mu.Lock()
+		l.next = l.next.Add(-l.interval)
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,32 @@
+package ollama
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterWaitReturnsCanceledReservation(t *testing.T) {
+	limiter := NewLimiter(10, 1) // one request every 100ms
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each of these gives up long before its turn
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if err := limiter.Wait(ctx); err == nil {
+			t.Fatal("Wait succeeded without waiting its turn")
+		}
+		cancel()
+	}
+
+	// Had the canceled waits kept their reservations, this would wait 600ms
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited > 300*time.Millisecond {
+		t.Errorf("waited %s after canceled waits, want about one interval", waited)
+	}
+}
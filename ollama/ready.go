@@ -0,0 +1,64 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Polling bounds for WaitUntilReady: the first retry comes quickly, and the
+// delay doubles up to a few seconds while the server keeps refusing
+const (
+	readyPollDelay    = 250 * time.Millisecond
+	maxReadyPollDelay = 5 * time.Second
+)
+
+// WaitUntilReady polls the default server until it answers or timeout elapses
+func WaitUntilReady(ctx context.Context, timeout time.Duration) error {
+	return defaultClient.WaitUntilReady(ctx, timeout)
+}
+
+// WaitUntilReady polls /api/tags with exponential backoff until the server
+// answers, timeout elapses, or ctx is done. A timeout of 0 checks once.
+func (c *Client) WaitUntilReady(ctx context.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		return c.ping(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := readyPollDelay
+	for {
+		err := c.ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ollama at %s not ready after %s: %w", c.BaseURL, timeout, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReadyPollDelay)
+	}
+}
+
+// ping asks the server for its model list, succeeding on any 200 answer
+func (c *Client) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("making HTTP request: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama API error (status %d)", resp.StatusCode)
+	}
+	return nil
+}
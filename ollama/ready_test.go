@@ -0,0 +1,56 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitUntilReadyPollsUntilServerAnswers(t *testing.T) {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Still starting for the first two polls
+		if polls.Add(1) < 3 {
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"models": []}`))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	if err := NewClient(server.URL).WaitUntilReady(context.Background(), 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if n := polls.Load(); n != 3 {
+		t.Errorf("polled %d times, want 3", n)
+	}
+	// Waits of 250ms then 500ms between the polls
+	if elapsed := time.Since(start); elapsed < readyPollDelay*3 {
+		t.Errorf("ready after %s, want the polls spaced by a doubling delay", elapsed)
+	}
+}
+
+func TestWaitUntilReadyGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "starting", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL)
+
+	start := time.Now()
+	if err := client.WaitUntilReady(context.Background(), 300*time.Millisecond); err == nil {
+		t.Fatal("WaitUntilReady succeeded against a server that never came up")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("gave up after %s, want about the timeout", elapsed)
+	}
+
+	// A timeout of 0 checks once
+	if err := client.WaitUntilReady(context.Background(), 0); err == nil {
+		t.Error("single check succeeded against a server that isn't up")
+	}
+}
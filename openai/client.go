@@ -0,0 +1,171 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the OpenAI API. Any server implementing the chat
+// completions endpoint (vLLM, LM Studio, llama.cpp, ...) can be used instead.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// DefaultModel is used when neither the client nor the request names a model
+const DefaultModel = "gpt-4o-mini"
+
+// DefaultTimeout is the Timeout NewClient sets
+const DefaultTimeout = 5 * time.Minute
+
+// Message is one entry of a chat conversation
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest is the body of POST /chat/completions. Unset sampling values
+// are omitted so the server's defaults apply.
+type ChatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	Seed        int       `json:"seed,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+}
+
+// chatResponse is the part of the chat completions response we use
+type chatResponse struct {
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Client talks to an OpenAI-compatible chat completions API. Timeout bounds
+// each HTTP request, reading the response included; 0 disables it. Set it
+// before the client's first request.
+type Client struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+
+	httpOnce sync.Once
+	http     *http.Client
+}
+
+// NewClient returns a client for the API at baseURL. An empty baseURL
+// selects DefaultBaseURL and an empty model selects DefaultModel.
+func NewClient(baseURL, apiKey, model string) *Client {
+	if strings.TrimSpace(baseURL) == "" {
+		baseURL = DefaultBaseURL
+	}
+	if strings.TrimSpace(model) == "" {
+		model = DefaultModel
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		Timeout: DefaultTimeout,
+	}
+}
+
+// httpClient returns the client's HTTP client, created on first use so its
+// connections are kept alive and reused across requests
+func (c *Client) httpClient() *http.Client {
+	c.httpOnce.Do(func() {
+		c.http = &http.Client{Timeout: c.Timeout}
+	})
+	return c.http
+}
+
+// NewClientFromEnv configures a client from OPENAI_BASE_URL, OPENAI_API_KEY,
+// and OPENAI_MODEL
+func NewClientFromEnv() *Client {
+	return NewClient(os.Getenv("OPENAI_BASE_URL"), os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_MODEL"))
+}
+
+// Generate sends prompt as a single user message and returns the reply
+func (c *Client) Generate(prompt string) (string, error) {
+	return c.Chat(context.Background(), ChatRequest{
+		Messages: []Message{{Role: "user", Content: prompt}},
+	})
+}
+
+// Chat sends a fully specified request and returns the first choice's
+// content. An empty Model selects the client's model. If ctx expires first,
+// the returned error wraps ctx.Err().
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (string, error) {
+	if req.Model == "" {
+		req.Model = c.Model
+	}
+	if len(req.Messages) == 0 {
+		return "", fmt.Errorf("chat request has no messages")
+	}
+
+	start := time.Now()
+	log.Printf("openai: chat request started model=%s at=%s", req.Model, start.Format(time.RFC3339Nano))
+
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return "", logFailure(req.Model, start, fmt.Errorf("marshaling request: %w", err))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		return "", logFailure(req.Model, start, fmt.Errorf("building HTTP request: %w", err))
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.httpClient().Do(httpReq)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("openai request aborted after %s: %w", time.Since(start).Round(time.Millisecond), ctxErr)
+		} else {
+			err = fmt.Errorf("making HTTP request: %w", err)
+		}
+		return "", logFailure(req.Model, start, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", logFailure(req.Model, start, fmt.Errorf("reading response body: %w", err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", logFailure(req.Model, start, fmt.Errorf("openai API error (status %d): %s", resp.StatusCode, string(body)))
+	}
+
+	var chat chatResponse
+	if err := json.Unmarshal(body, &chat); err != nil {
+		return "", logFailure(req.Model, start, fmt.Errorf("unmarshaling response: %w", err))
+	}
+	if chat.Error != nil {
+		return "", logFailure(req.Model, start, fmt.Errorf("openai API error: %s", chat.Error.Message))
+	}
+	if len(chat.Choices) == 0 {
+		return "", logFailure(req.Model, start, fmt.Errorf("response has no choices"))
+	}
+
+	log.Printf("openai: chat request completed model=%s elapsed=%s", req.Model, time.Since(start))
+	return chat.Choices[0].Message.Content, nil
+}
+
+func logFailure(model string, start time.Time, err error) error {
+	log.Printf("openai: chat request failed model=%s err=%v elapsed=%s", model, err, time.Since(start))
+	return err
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"kommunity/agents"
+)
+
+// dataPaths locates one community's files under a data directory, so several
+// independent communities can be run side by side
+type dataPaths struct {
+	root string
+}
+
+func (p dataPaths) community() string { return filepath.Join(p.root, "community") }
+func (p dataPaths) agents() string    { return filepath.Join(p.root, "agents.json") }
+func (p dataPaths) agentsDir() string { return filepath.Join(p.root, "agents") }
+func (p dataPaths) config() string    { return filepath.Join(p.root, "config.json") }
+func (p dataPaths) memory() string    { return filepath.Join(p.root, "memory.json") }
+func (p dataPaths) blocklist() string { return filepath.Join(p.root, "blocklist.txt") }
+func (p dataPaths) images() string    { return filepath.Join(p.root, "images") }
+func (p dataPaths) prompts() string   { return filepath.Join(p.root, "prompts.json") }
+
+// loadAgents reads the agents/ directory, one file per agent, when the data
+// directory has one, and agents.json otherwise
+func (p dataPaths) loadAgents() ([]agents.Agent, error) {
+	if info, err := os.Stat(p.agentsDir()); err == nil && info.IsDir() {
+		return agents.LoadAgentsDir(p.agentsDir())
+	}
+	return agents.LoadAgents(p.agents())
+}
+
+// envOr returns the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
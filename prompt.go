@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"kommunity/agents"
+	"kommunity/community"
+	"kommunity/ollama"
+)
+
+// contextOptions controls how a thread is condensed into a reply prompt
+type contextOptions struct {
+	summarizeOld  bool // replace older replies with a model-written summary
+	recentReplies int  // replies kept verbatim when summarizing
+	maxReplies    int  // most recent replies included at all; 0 includes all
+	maxChars      int  // size budget for the whole rendered thread; 0 is unlimited
+}
+
+// omittedMarker stands in for replies left out of a reply prompt
+const omittedMarker = "[earlier replies omitted]"
+
+// buildReplyContext renders the topic and its replies for a reply prompt.
+// When summarization is enabled, replies older than the most recent few are
+// collapsed into a single summary entry that is cached on the topic;
+// otherwise only the most recent maxReplies are included.
+func (s *simulator) buildReplyContext(ctx context.Context, gen Generator, topic *community.Topic) string {
+	return s.buildThreadContext(ctx, gen, topic, s.replyContext)
+}
+
+// buildThreadContext is buildReplyContext with explicit context options
+func (s *simulator) buildThreadContext(ctx context.Context, gen Generator, topic *community.Topic, opts contextOptions) string {
+	thread := fmt.Sprintf("Original Topic: %s\n\n%s", topic.Title, topic.Body)
+	if len(topic.Replies) == 0 {
+		return thread
+	}
+
+	var lead string // summary or omission marker shown before the replies
+	replies := topic.Replies
+
+	keep := max(0, opts.recentReplies)
+	if opts.summarizeOld && len(replies) > keep {
+		older := replies[:len(replies)-keep]
+		summary, err := s.summarizeReplies(ctx, gen, topic, older)
+		if err != nil {
+			fmt.Printf("   ⚠️  Summarizing earlier replies failed, using them verbatim: %v\n", err)
+		} else {
+			lead = fmt.Sprintf("Summary of the first %d replies: %s", len(older), summary)
+			replies = replies[len(older):]
+		}
+	}
+
+	// Without a summary standing in for them, the oldest replies are dropped
+	if limit := opts.maxReplies; limit > 0 && len(replies) > limit && lead == "" {
+		replies = replies[len(replies)-limit:]
+		lead = omittedMarker
+	}
+
+	lines := make([]string, len(replies))
+	for i, reply := range replies {
+		lines[i] = fmt.Sprintf("%s: %s", reply.Author, reply.Content)
+	}
+
+	thread += "\n\nPrevious Replies:\n"
+	if budget := opts.maxChars; budget > 0 {
+		lead, lines = fitContext(budget-len(thread), lead, lines)
+	}
+	if lead != "" {
+		lines = append([]string{lead}, lines...)
+	}
+	for i, line := range lines {
+		thread += fmt.Sprintf("%d. %s\n", i+1, line)
+	}
+	return thread
+}
+
+// fitContext keeps the most recent reply lines that fit in budget characters,
+// always keeping the newest one. If older lines or the lead don't fit, the
+// lead becomes omittedMarker. Without lines, e.g. when a summary stands in
+// for every reply, the lead is kept as is.
+func fitContext(budget int, lead string, lines []string) (string, []string) {
+	if len(lines) == 0 {
+		return lead, lines
+	}
+
+	// Each line is rendered as "N. line\n"
+	cost := func(line string) int { return len(line) + 6 }
+
+	first := len(lines) - 1
+	used := cost(lines[first])
+	for first > 0 && used+cost(lines[first-1]) <= budget {
+		first--
+		used += cost(lines[first])
+	}
+	if first == 0 && (lead == "" || used+cost(lead) <= budget) {
+		return lead, lines
+	}
+
+	// Make room for the marker, still keeping the newest reply
+	for first < len(lines)-1 && used+cost(omittedMarker) > budget {
+		used -= cost(lines[first])
+		first++
+	}
+	return omittedMarker, lines[first:]
+}
+
+// summarizeReplies condenses older replies, reusing the summary cached on the
+// topic while the number of summarized replies is unchanged.
+func (s *simulator) summarizeReplies(ctx context.Context, gen Generator, topic *community.Topic, older []community.Reply) (string, error) {
+	if cached := topic.ContextSummary; cached != nil && cached.ReplyCount == len(older) {
+		return cached.Text, nil
+	}
+
+	var b strings.Builder
+	for _, reply := range older {
+		fmt.Fprintf(&b, "%s: %s\n", reply.Author, reply.Content)
+	}
+	prompt := fmt.Sprintf("Summarize the following discussion about %q in 2-3 sentences, noting who argued what:\n\n%s", topic.Title, b.String())
+
+	summary, err := s.complete(ctx, gen, "", "", prompt, ollama.Options{})
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+
+	topic.ContextSummary = &community.ContextSummary{ReplyCount: len(older), Text: summary}
+	err = community.UpdateTopic(s.communityDir, topic.Filename, func(t *community.Topic) {
+		t.ContextSummary = topic.ContextSummary
+	})
+	if err != nil {
+		fmt.Printf("   ⚠️  Caching context summary failed: %v\n", err)
+	}
+	return summary, nil
+}
+
+// Names of the prompt templates that data/prompts.json may override
+const (
+	promptCreateTopic = "create_topic"
+	promptReply       = "reply"
+	promptQuoteReply  = "quote_reply"
+)
+
+// defaultPrompts are the built-in prompt templates, used for any name the
+// prompts file leaves out
+var defaultPrompts = map[string]string{
+	promptCreateTopic: "Create an interesting discussion topic for our community. Answer in this format:\n\nTitle: <a short headline, under 12 words>\nBody: <2-4 sentences that open the discussion>",
+	promptReply:       "Here is the ongoing discussion:\n\n{{ .Thread }}\n\nPlease provide a thoughtful reply that adds value to this conversation. Keep your response to 1-2 sentences.",
+	promptQuoteReply:  "Here is the ongoing discussion:\n\n{{ .Thread }}\n\n{{ .Quoted.Author }} wrote:\n\n{{ .Quote }}\n\nReply directly to {{ .Quoted.Author }}'s comment above: agree, push back, or build on their specific point, and address them by name. Keep your response to 1-2 sentences.",
+}
+
+// promptData is what prompt templates are rendered with
+type promptData struct {
+	Agent    agents.Agent
+	Domain   string          // the community's domain from config.json
+	Language string          // name of the language to write in, e.g. "Italian"; empty is English
+	Topic    community.Topic // the topic being replied to; empty for create_topic
+	Thread   string          // the topic and its replies, condensed to the context limits
+	Quoted   community.Reply // the comment being answered, for quote_reply
+	Quote    string          // Quoted's content as "> " lines
+}
+
+// promptSet holds the parsed prompt templates by name
+type promptSet struct {
+	templates map[string]*template.Template
+	custom    map[string]bool // names overridden by the prompts file
+}
+
+// loadPrompts parses the built-in prompts, overridden by any templates in
+// the JSON object at path ({"reply": "...", ...}). A missing file keeps the
+// built-ins. Every template is test-rendered so mistakes such as unknown
+// fields surface at startup rather than mid-run.
+func loadPrompts(path string) (promptSet, error) {
+	sources := make(map[string]string, len(defaultPrompts))
+	for name, text := range defaultPrompts {
+		sources[name] = text
+	}
+	custom := make(map[string]bool)
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return promptSet{}, fmt.Errorf("reading prompts: %w", err)
+	}
+	if err == nil {
+		var overrides map[string]string
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return promptSet{}, fmt.Errorf("decoding prompts in %s: %w", path, err)
+		}
+		for name, text := range overrides {
+			if _, ok := defaultPrompts[name]; !ok {
+				return promptSet{}, fmt.Errorf("unknown prompt %q in %s (known: %s)", name, path, strings.Join(promptNames(), ", "))
+			}
+			sources[name] = text
+			custom[name] = true
+		}
+	}
+
+	prompts := promptSet{templates: make(map[string]*template.Template, len(sources)), custom: custom}
+	sample := promptData{Quoted: community.Reply{Author: "someone", Content: "a point"}, Quote: "> a point"}
+	for name, text := range sources {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return promptSet{}, fmt.Errorf("parsing prompt %s: %w", name, err)
+		}
+		if err := tmpl.Execute(new(strings.Builder), sample); err != nil {
+			return promptSet{}, fmt.Errorf("rendering prompt %s: %w", name, err)
+		}
+		prompts.templates[name] = tmpl
+	}
+	return prompts, nil
+}
+
+// promptNames lists the prompt template names, sorted
+func promptNames() []string {
+	names := make([]string, 0, len(defaultPrompts))
+	for name := range defaultPrompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// render fills in the named prompt template
+func (p promptSet) render(name string, data promptData) (string, error) {
+	var b strings.Builder
+	if err := p.templates[name].Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering %s prompt: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// languageInstruction returns the instruction appended to the named prompt
+// for writing in lang. Custom templates say it themselves with
+// {{ .Language }}, so only the built-ins get one.
+func (p promptSet) languageInstruction(name, lang string) string {
+	if p.custom[name] {
+		return ""
+	}
+	return languageInstruction(lang, name == promptCreateTopic)
+}
+
+// quoteLines formats a reply's content as a Markdown blockquote
+func quoteLines(content string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(content), "\n", "\n> ")
+}
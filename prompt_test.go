@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kommunity/community"
+	"kommunity/ollama"
+)
+
+func TestFitContextWithoutLines(t *testing.T) {
+	lead, lines := fitContext(16000, "Summary of the first 3 replies: ...", nil)
+	if lead != "Summary of the first 3 replies: ..." || len(lines) != 0 {
+		t.Fatalf("fitContext = %q, %q; want the lead and no lines", lead, lines)
+	}
+}
+
+func TestFitContextKeepsNewestLines(t *testing.T) {
+	lines := []string{strings.Repeat("a", 50), strings.Repeat("b", 50), strings.Repeat("c", 50)}
+
+	lead, kept := fitContext(1000, "", lines)
+	if lead != "" || len(kept) != 3 {
+		t.Errorf("with room for everything got %q, %d lines; want no lead and 3 lines", lead, len(kept))
+	}
+
+	lead, kept = fitContext(120, "", lines)
+	if lead != omittedMarker {
+		t.Errorf("lead = %q, want %q", lead, omittedMarker)
+	}
+	if len(kept) == 0 || kept[len(kept)-1] != lines[2] {
+		t.Errorf("kept %q, want the newest line last", kept)
+	}
+}
+
+func TestBuildReplyContextSummarizesOldReplies(t *testing.T) {
+	dir := t.TempDir()
+	topic := community.Topic{Title: "Knives", Body: "Which one first?", Author: "alice", Timestamp: time.Now().Format(time.RFC3339)}
+	for i := 1; i <= 5; i++ {
+		topic.Replies = append(topic.Replies, community.Reply{Author: "bob", Content: fmt.Sprintf("opinion %d", i), Timestamp: time.Now().Format(time.RFC3339)})
+	}
+	topic, err := community.CreateTopic(dir, topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	gen := generatorFunc(func(ctx context.Context, req ollama.Request) (string, error) {
+		calls++
+		if !strings.Contains(req.Prompt, "opinion 1") || strings.Contains(req.Prompt, "opinion 4") {
+			t.Errorf("summary prompt should cover only the older replies:\n%s", req.Prompt)
+		}
+		return "Bob walked through three opinions.", nil
+	})
+	s := &simulator{
+		communityDir: dir,
+		replyContext: contextOptions{summarizeOld: true, recentReplies: 2, maxChars: 16000},
+	}
+
+	thread := s.buildReplyContext(context.Background(), gen, &topic)
+	if !strings.Contains(thread, "Summary of the first 3 replies: Bob walked through three opinions.") {
+		t.Errorf("thread has no summary of the first 3 replies:\n%s", thread)
+	}
+	for i := 1; i <= 3; i++ {
+		if strings.Contains(thread, fmt.Sprintf("opinion %d", i)) {
+			t.Errorf("old reply %d is still verbatim:\n%s", i, thread)
+		}
+	}
+	for i := 4; i <= 5; i++ {
+		if !strings.Contains(thread, fmt.Sprintf("bob: opinion %d", i)) {
+			t.Errorf("recent reply %d is missing:\n%s", i, thread)
+		}
+	}
+
+	// The summary is cached on the topic file for the same reply count
+	saved, err := community.LoadTopicByRelativePath(dir, topic.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.buildReplyContext(context.Background(), gen, &saved)
+	if calls != 1 {
+		t.Errorf("model called %d times, want 1 with the cached summary reused", calls)
+	}
+}
+
+func TestLanguageInstructionOnlyForBuiltInPrompts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.json")
+	custom := `{"reply": "{{ .Thread }}\n\nReply in {{ .Language }}."}`
+	if err := os.WriteFile(path, []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prompts, err := loadPrompts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := prompts.render(promptReply, promptData{Language: languageName("it"), Thread: "thread"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt += prompts.languageInstruction(promptReply, "it")
+	if n := strings.Count(prompt, "Italian"); n != 1 {
+		t.Errorf("custom reply prompt %q names the language %d times, want once", prompt, n)
+	}
+
+	if got := prompts.languageInstruction(promptCreateTopic, "it"); !strings.Contains(got, "Italian") {
+		t.Errorf("built-in create_topic instruction = %q, want it to ask for Italian", got)
+	}
+	if got := prompts.languageInstruction(promptQuoteReply, "en"); got != "" {
+		t.Errorf("English instruction = %q, want none", got)
+	}
+}
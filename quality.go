@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kommunity/community"
+	"kommunity/ollama"
+)
+
+// qualityGate controls the optional self-critique pass that asks the model to
+// grade its own output and regenerate weak posts.
+type qualityGate struct {
+	enabled     bool
+	minScore    int
+	maxAttempts int
+}
+
+// generateContent produces text for prompt under the given system message,
+// cleaned up by community.SanitizeGeneration. With the quality gate enabled
+// each candidate is rated 1-5 by the model and regenerated until it reaches
+// the minimum score or attempts run out, in which case the best candidate
+// wins. If a later attempt fails, the best candidate so far is kept; the
+// error is only returned when nothing was generated. Any images are shown to
+// the model with the prompt. Candidates come from model, or the default model
+// when it's empty; ratings always use the default.
+func (s *simulator) generateContent(ctx context.Context, gen Generator, model, system, prompt string, opts ollama.Options, images ...[]byte) (string, *community.Debug, error) {
+	if !s.quality.enabled {
+		content, err := s.complete(ctx, gen, model, system, prompt, opts, images...)
+		return community.SanitizeGeneration(content), nil, err
+	}
+
+	attempts := max(1, s.quality.maxAttempts)
+	var best string
+	bestScore := -1
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		content, err := s.complete(ctx, gen, model, system, prompt, opts, images...)
+		if err != nil {
+			if bestScore < 0 {
+				return "", nil, err
+			}
+			fmt.Printf("   ⚠️  Regenerating failed, keeping the best attempt: %v\n", err)
+			return best, &community.Debug{QualityScore: bestScore, QualityAttempts: attempt - 1}, nil
+		}
+		content = community.SanitizeGeneration(content)
+
+		score, err := s.rateContent(ctx, gen, prompt, content)
+		if err != nil {
+			fmt.Printf("   ⚠️  Quality rating failed: %v\n", err)
+		}
+		fmt.Printf("   🧪 Quality score %d/5 (attempt %d/%d)\n", score, attempt, attempts)
+
+		if score > bestScore {
+			best, bestScore = content, score
+		}
+		if score >= s.quality.minScore {
+			return content, &community.Debug{QualityScore: score, QualityAttempts: attempt}, nil
+		}
+	}
+
+	return best, &community.Debug{QualityScore: bestScore, QualityAttempts: attempts}, nil
+}
+
+// rateContent asks the model to score content against the prompt that
+// produced it. Unparseable ratings score 0.
+func (s *simulator) rateContent(ctx context.Context, gen Generator, prompt, content string) (int, error) {
+	ratingPrompt := fmt.Sprintf("Rate the following community post for quality and relevance to its instructions on a scale of 1 (low effort) to 5 (excellent). Answer with a single digit only.\n\nInstructions:\n%s\n\nPost:\n%s", prompt, content)
+
+	rating, err := s.complete(ctx, gen, "", "", ratingPrompt, ollama.Options{})
+	if err != nil {
+		return 0, fmt.Errorf("rating content: %w", err)
+	}
+	return parseScore(rating), nil
+}
+
+// parseScore returns the first digit between 1 and 5 found in the model's
+// rating, or 0 when there is none.
+func parseScore(rating string) int {
+	for _, r := range strings.TrimSpace(rating) {
+		if r >= '1' && r <= '5' {
+			return int(r - '0')
+		}
+	}
+	return 0
+}
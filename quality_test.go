@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"kommunity/ollama"
+)
+
+// scriptedGenerator answers rating prompts with the next score and other
+// prompts with the next post, failing once the posts run out
+func scriptedGenerator(posts, scores []string) Generator {
+	return generatorFunc(func(ctx context.Context, req ollama.Request) (string, error) {
+		if strings.HasPrefix(req.Prompt, "Rate the following") {
+			score := scores[0]
+			scores = scores[1:]
+			return score, nil
+		}
+		if len(posts) == 0 {
+			return "", errors.New("model unavailable")
+		}
+		post := posts[0]
+		posts = posts[1:]
+		return post, nil
+	})
+}
+
+func TestGenerateContentRegeneratesLowScores(t *testing.T) {
+	s := &simulator{quality: qualityGate{enabled: true, minScore: 4, maxAttempts: 3}}
+	gen := scriptedGenerator([]string{"meh", "great post"}, []string{"2", "5"})
+
+	content, debug, err := s.generateContent(context.Background(), gen, "", "", "Write a post", ollama.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "great post" {
+		t.Errorf("content = %q, want the second, higher-scoring attempt", content)
+	}
+	if debug == nil || debug.QualityScore != 5 || debug.QualityAttempts != 2 {
+		t.Errorf("debug = %+v, want score 5 after 2 attempts", debug)
+	}
+}
+
+func TestGenerateContentKeepsBestWhenRetryFails(t *testing.T) {
+	s := &simulator{quality: qualityGate{enabled: true, minScore: 4, maxAttempts: 3}}
+	gen := scriptedGenerator([]string{"meh"}, []string{"2"})
+
+	content, debug, err := s.generateContent(context.Background(), gen, "", "", "Write a post", ollama.Options{})
+	if err != nil {
+		t.Fatalf("err = %v, want the scored attempt kept", err)
+	}
+	if content != "meh" {
+		t.Errorf("content = %q, want %q", content, "meh")
+	}
+	if debug == nil || debug.QualityScore != 2 || debug.QualityAttempts != 1 {
+		t.Errorf("debug = %+v, want score 2 after 1 attempt", debug)
+	}
+}
+
+func TestGenerateContentFailsWithoutAnyAttempt(t *testing.T) {
+	s := &simulator{quality: qualityGate{enabled: true, minScore: 4, maxAttempts: 3}}
+	gen := scriptedGenerator(nil, nil)
+
+	if _, _, err := s.generateContent(context.Background(), gen, "", "", "Write a post", ollama.Options{}); err == nil {
+		t.Fatal("err = nil, want the generation error")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"kommunity/community"
+)
+
+var (
+	titleLabel = regexp.MustCompile(`(?i)^(?:\*\*)?title(?:\*\*)?\s*:\s*(?:\*\*)?`)
+	bodyLabel  = regexp.MustCompile(`(?im)^(?:\*\*)?body(?:\*\*)?\s*:\s*(?:\*\*)?`)
+)
+
+// splitTopic separates a generated topic into its title and body. It expects
+// "Title: ..." and "Body: ..." sections, and otherwise takes the first line
+// as the title and the rest as the body. When no body can be found, the
+// whole text is used for both.
+func splitTopic(content string) (title, body string) {
+	content = strings.TrimSpace(content)
+
+	if loc := bodyLabel.FindStringIndex(content); loc != nil {
+		title, body = content[:loc[0]], content[loc[1]:]
+	} else if first, rest, found := strings.Cut(content, "\n"); found {
+		title, body = first, rest
+	}
+
+	title = strings.Trim(community.SanitizeGeneration(titleLabel.ReplaceAllString(strings.TrimSpace(title), "")), "*# ")
+	body = community.SanitizeGeneration(body)
+	if title == "" || body == "" {
+		return content, content
+	}
+	return title, body
+}
@@ -1,129 +1,1042 @@
 package main
 
 import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"kommunity/agents"
 	"kommunity/community"
+	"kommunity/metrics"
+	"kommunity/ollama"
 )
 
 type topicSummary struct {
 	Title      string
 	Author     string
+	AuthorName string
 	Timestamp  string
 	When       string
 	Snippet    string
+	Board      string // as stored; empty for topics never filed under a board
+	Lang       string
 	Tags       []string
 	ReplyCount int
 	Path       string
 }
 
 type topicDetail struct {
-	Title     string
-	Body      string
-	Author    string
-	Timestamp string
-	When      string
-	Tags      []string
-	Replies   []community.Reply
+	Title      string
+	Body       string
+	Author     string
+	AuthorName string
+	Timestamp  string
+	When       string
+	EditedAt   string
+	Board      string
+	Lang       string
+	Archived   bool
+	Tags       []string
+	Image      string
+	Replies    []community.Reply
+	Thread     []*community.ReplyNode
 }
 
-func runServer(addr string) error {
-	router := gin.Default()
-	router.SetFuncMap(template.FuncMap{
-		"formatTime": formatTime,
+// agentRow is one author's line on the /agents page
+type agentRow struct {
+	ID    string
+	Name  string
+	Style string
+	community.AgentActivity
+}
+
+// topicForm holds the fields of the new topic form, so they can be shown
+// again when validation fails.
+type topicForm struct {
+	Title  string
+	Body   string
+	Author string
+	Tags   string
+}
+
+// replyForm holds the fields of a thread's reply form
+type replyForm struct {
+	Author  string
+	Content string
+}
+
+// defaultAuthor is credited for web posts that leave the author blank
+const defaultAuthor = "anonymous"
+
+// pagination links the pages of a listing
+type pagination struct {
+	Page    int
+	Pages   int
+	PrevURL string
+	NextURL string
+}
+
+// topicsPerPage is the page size of the index listing
+const topicsPerPage = 20
+
+// sseHeartbeat is how often an idle /events stream sends a comment line so
+// proxies don't close the connection.
+const sseHeartbeat = 15 * time.Second
+
+// healthProbeTimeout bounds how long /healthz waits for Ollama to answer
+const healthProbeTimeout = 2 * time.Second
+
+// shutdownTimeout bounds how long in-flight requests may take to finish once
+// the server is asked to stop
+const shutdownTimeout = 10 * time.Second
+
+// serverOptions configures the web interface
+type serverOptions struct {
+	addr         string
+	basePath     string // URL prefix when hosted behind a reverse proxy, e.g. "/kommunity"
+	maxBodyBytes int64  // request body cap for write endpoints
+	dev          bool   // reload templates from disk when they change
+	data         dataPaths
+	corsOrigins  []string // origins allowed to call /api from a browser; none disables CORS
+
+	// step runs one agent turn for POST /simulate/step; nil leaves the
+	// endpoint off
+	step func(ctx context.Context) (actionResult, error)
+
+	// summarize returns a model-written summary of a thread for
+	// GET /topic/<id>/summary, and whether it came from the cache; nil leaves
+	// the endpoint off
+	summarize func(ctx context.Context, topic community.Topic) (string, bool, error)
+
+	// ollamaRunning reports whether Ollama answers, for /healthz; nil probes
+	// the default client
+	ollamaRunning func(ctx context.Context) bool
+}
+
+// Limits on stored content submitted through the write endpoints, in runes
+const (
+	maxTitleLength   = 300
+	maxContentLength = 10000 // topic bodies and reply content
+)
+
+// runServer serves the web interface until ctx is done, then shuts down
+// gracefully
+func runServer(ctx context.Context, opts serverOptions) error {
+	router, err := newRouter(opts)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:    opts.addr,
+		Handler: router,
+		// Requests inherit ctx, so open event streams end on shutdown
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("web: shutdown: %v", err)
+		}
+	}()
+
+	log.Printf("web: listening on %s", opts.addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	// Let in-flight requests finish
+	<-shutdownDone
+	return nil
+}
+
+func newRouter(opts serverOptions) (*gin.Engine, error) {
+	basePath := normalizeBasePath(opts.basePath)
+	communityDir := opts.data.community()
+
+	// Agents are optional for the viewer; without them authors are shown as stored
+	agentList, err := opts.data.loadAgents()
+	if err != nil {
+		log.Printf("web: loading agents: %v", err)
+	}
+	aliases := agents.NameIndex(agentList)
+	agentsByID := agents.IDIndex(agentList)
+
+	templates, err := newTemplateSet("web/templates/*.tmpl", template.FuncMap{
+		"formatTime":     formatTime,
+		"renderMarkdown": renderMarkdown,
+		"authorName": func(id string) string {
+			return authorName(agentsByID, id)
+		},
+		"path": func(p string) string {
+			return basePath + p
+		},
+		"linkMentions": func(content string, mentions []string) string {
+			return linkMentions(basePath, content, mentions)
+		},
 	})
-	router.LoadHTMLGlob("web/templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if opts.dev {
+		log.Printf("dev: watching web/templates and web/static for changes")
+		templates.watch([]string{filepath.Join("web", "templates"), filepath.Join("web", "static")}, time.Second)
+	}
 
-	router.GET("/", func(c *gin.Context) {
-		topics, err := community.LoadTopics("data/community")
+	router := gin.Default()
+	router.HTMLRender = templates
+
+	// Pages rendered by an earlier run may have used other templates, so
+	// validators never predate this router. With -dev templates change under
+	// a running server, so pages are always sent in full.
+	started := time.Now()
+
+	routes := router.Group(basePath)
+
+	// renderTopic shows a thread page, with the reply form refilled and an
+	// error message when a submission was rejected
+	renderTopic := func(c *gin.Context, status int, topic community.Topic, form replyForm, errMsg string) {
+		community.NormalizeAuthors(&topic, aliases)
+
+		thread := community.BuildReplyTree(topic.Replies)
+		replySort := c.Query("replies")
+		if replySort == "top" {
+			community.SortReplyTree(thread)
+		} else {
+			replySort = "oldest"
+		}
+
+		detail := topicDetail{
+			Title:      topic.Title,
+			Body:       topic.Body,
+			Author:     topic.Author,
+			AuthorName: authorName(agentsByID, topic.Author),
+			Timestamp:  topic.Timestamp,
+			When:       formatTime(topic.Timestamp),
+			EditedAt:   topic.EditedAt,
+			Board:      topic.Board,
+			Lang:       topic.Lang,
+			Archived:   topic.Archived,
+			Tags:       topic.Tags,
+			Image:      topic.Image,
+			Replies:    topic.Replies,
+			Thread:     thread,
+		}
+
+		c.HTML(status, "topic.tmpl", gin.H{
+			"Topic":     detail,
+			"FilePath":  filepath.ToSlash(topic.Filename),
+			"LinkPath":  topicURL(basePath, topic),
+			"ReplyForm": form,
+			"ReplySort": replySort,
+			"Error":     errMsg,
+		})
+	}
+
+	routes.GET("/", func(c *gin.Context) {
+		page := pageNumber(c.Query("page"))
+		order, listURL := community.SortNew, basePath+"/"
+		if c.Query("sort") == community.SortActive {
+			order, listURL = community.SortActive, basePath+"/?sort="+community.SortActive
+		}
+		archived := c.Query("archived") == "true"
+
+		var topics []community.Topic
+		var total int
+		var err error
+		if archived {
+			// Archived topics are listed newest first only
+			order, listURL = community.SortNew, basePath+"/?archived=true"
+			topics, err = community.LoadArchivedTopics(communityDir)
+			total = len(topics)
+			topics = topics[min((page-1)*topicsPerPage, total):min(page*topicsPerPage, total)]
+		} else {
+			topics, total, err = community.LoadTopicsPaged(communityDir, order, (page-1)*topicsPerPage, topicsPerPage)
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, "failed to load topics: %v", err)
+			return
+		}
+
+		all, err := community.LoadTopics(communityDir)
 		if err != nil {
 			c.String(http.StatusInternalServerError, "failed to load topics: %v", err)
 			return
 		}
+		boards := community.CountBoards(all)
 
-		summaries := make([]topicSummary, 0, len(topics))
+		summaries := summarizeTopics(topics, aliases, agentsByID, basePath)
+		modified := started
 		for _, t := range topics {
-			summaries = append(summaries, topicSummary{
-				Title:      t.Title,
-				Author:     t.Author,
-				Timestamp:  t.Timestamp,
-				When:       formatTime(t.Timestamp),
-				Snippet:    buildSnippet(t.Body),
-				Tags:       t.Tags,
-				ReplyCount: len(t.Replies),
-				Path:       toURLPath(t.Filename),
+			modified = latest(modified, community.ActivityTime(t))
+		}
+		if !opts.dev && notModified(c, contentETag(started, order, archived, page, total, summaries, boards), modified) {
+			return
+		}
+
+		if archived {
+			c.HTML(http.StatusOK, "index.tmpl", gin.H{
+				"Topics":     summaries,
+				"Count":      total,
+				"Archived":   true,
+				"Pagination": newPagination(listURL, page, total),
 			})
+			return
 		}
+		c.HTML(http.StatusOK, "index.tmpl", gin.H{
+			"Topics":     summaries,
+			"Count":      total,
+			"Sort":       order,
+			"Pagination": newPagination(listURL, page, total),
+			"Boards":     boards,
+			// New topics are prepended live only where they belong on top
+			"Live": page == 1 && order == community.SortNew,
+		})
+	})
 
+	routes.GET("/tag/:name", func(c *gin.Context) {
+		tag := c.Param("name")
+		topics, err := community.LoadTopicsByTag(communityDir, tag)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "failed to load topics: %v", err)
+			return
+		}
+
+		summaries := summarizeTopics(topics, aliases, agentsByID, basePath)
 		c.HTML(http.StatusOK, "index.tmpl", gin.H{
 			"Topics": summaries,
 			"Count":  len(summaries),
+			"Tag":    tag,
 		})
 	})
 
-	router.GET("/topic/*topicPath", func(c *gin.Context) {
+	routes.GET("/board/:name", func(c *gin.Context) {
+		board := community.BoardName(c.Param("name"))
+		topics, err := community.LoadTopicsByBoard(communityDir, board)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "failed to load topics: %v", err)
+			return
+		}
+
+		summaries := summarizeTopics(topics, aliases, agentsByID, basePath)
+		c.HTML(http.StatusOK, "index.tmpl", gin.H{
+			"Topics": summaries,
+			"Count":  len(summaries),
+			"Board":  board,
+		})
+	})
+
+	routes.GET("/search", func(c *gin.Context) {
+		query := strings.TrimSpace(c.Query("q"))
+		topics, err := community.SearchTopics(communityDir, query)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, community.ErrEmptyQuery) {
+				status = http.StatusBadRequest
+			}
+			c.String(status, "search failed: %v", err)
+			return
+		}
+
+		summaries := summarizeTopics(topics, aliases, agentsByID, basePath)
+		c.HTML(http.StatusOK, "index.tmpl", gin.H{
+			"Topics": summaries,
+			"Count":  len(summaries),
+			"Query":  query,
+		})
+	})
+
+	// summarizeTopic answers GET /topic/<id>/summary
+	summarizeTopic := func(c *gin.Context, topic community.Topic) {
+		if opts.summarize == nil {
+			c.String(http.StatusNotFound, "thread summaries are off; start the server with -summaries")
+			return
+		}
+		text, cached, err := opts.summarize(c.Request.Context(), topic)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"topic": topic.ID, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"topic":   topic.ID,
+			"replies": len(topic.Replies),
+			"summary": text,
+			"cached":  cached,
+		})
+	}
+
+	routes.GET("/topic/*topicPath", func(c *gin.Context) {
 		rel := strings.TrimPrefix(c.Param("topicPath"), "/")
 		if rel == "" {
-			c.Redirect(http.StatusFound, "/")
+			c.Redirect(http.StatusFound, basePath+"/")
 			return
 		}
 
-		topic, err := community.LoadTopicByRelativePath("data/community", rel)
+		editing := strings.HasSuffix(rel, "/edit")
+		rel = strings.TrimSuffix(rel, "/edit")
+		summary := !editing && strings.HasSuffix(rel, "/summary")
+		rel = strings.TrimSuffix(rel, "/summary")
+
+		topic, err := loadTopicRef(communityDir, rel)
 		if err != nil {
 			c.String(http.StatusNotFound, "topic not found: %v", err)
 			return
 		}
+		if summary {
+			summarizeTopic(c, topic)
+			return
+		}
+		if editing {
+			form := topicForm{Title: topic.Title, Body: topic.Body, Author: topic.Author, Tags: strings.Join(topic.Tags, ", ")}
+			c.HTML(http.StatusOK, "new.tmpl", withPage(editTopicPage(basePath, topic), gin.H{"Form": form}))
+			return
+		}
+		modified, err := community.TopicModTime(communityDir, topic.Filename)
+		if err != nil {
+			log.Printf("web: %v", err)
+		}
+		if !opts.dev && notModified(c, contentETag(started, topic, c.Query("replies")), latest(started, modified)) {
+			return
+		}
+		renderTopic(c, http.StatusOK, topic, replyForm{}, "")
+	})
 
-		detail := topicDetail{
-			Title:     topic.Title,
-			Body:      topic.Body,
-			Author:    topic.Author,
-			Timestamp: topic.Timestamp,
-			When:      formatTime(topic.Timestamp),
-			Tags:      topic.Tags,
-			Replies:   topic.Replies,
-		}
-
-		c.HTML(http.StatusOK, "topic.tmpl", gin.H{
-			"Topic":    detail,
-			"FilePath": filepath.ToSlash(topic.Filename),
-			"LinkPath": toURLPath(topic.Filename),
+	// postReply handles the thread page's reply form
+	postReply := func(c *gin.Context, ref string) {
+		topic, err := loadTopicRef(communityDir, ref)
+		if err != nil {
+			c.String(http.StatusNotFound, "topic not found: %v", err)
+			return
+		}
+
+		if err := c.Request.ParseForm(); err != nil {
+			renderTopic(c, bodyErrorStatus(err), topic, replyForm{}, fmt.Sprintf("Invalid form submission: %v", err))
+			return
+		}
+		form := replyForm{
+			Author:  strings.TrimSpace(c.PostForm("author")),
+			Content: strings.TrimSpace(c.PostForm("content")),
+		}
+		if form.Content == "" {
+			renderTopic(c, http.StatusBadRequest, topic, form, "Reply content is required.")
+			return
+		}
+		if err := checkLength("content", form.Content, maxContentLength); err != nil {
+			renderTopic(c, http.StatusBadRequest, topic, form, err.Error())
+			return
+		}
+
+		author := form.Author
+		if author == "" {
+			author = defaultAuthor
+		}
+		reply := community.Reply{
+			Author:    author,
+			Content:   form.Content,
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+		if err := community.AddReplyByPath(communityDir, topic.Filename, reply); err != nil {
+			if errors.Is(err, community.ErrTopicArchived) {
+				renderTopic(c, http.StatusConflict, topic, form, "This topic is archived and takes no new replies.")
+				return
+			}
+			renderTopic(c, http.StatusInternalServerError, topic, form, fmt.Sprintf("Saving the reply failed: %v", err))
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, topicURL(basePath, topic))
+	}
+
+	// editTopic saves changes from the edit form. The file keeps its name,
+	// so the topic's URL does not change.
+	editTopic := func(c *gin.Context, ref string) {
+		topic, err := loadTopicRef(communityDir, ref)
+		if err != nil {
+			c.String(http.StatusNotFound, "topic not found: %v", err)
+			return
+		}
+
+		page := editTopicPage(basePath, topic)
+		if err := c.Request.ParseForm(); err != nil {
+			c.HTML(bodyErrorStatus(err), "new.tmpl", withPage(page, gin.H{"Form": topicForm{}, "Error": fmt.Sprintf("Invalid form submission: %v", err)}))
+			return
+		}
+
+		form := readTopicForm(c)
+		if err := form.validate(); err != nil {
+			c.HTML(http.StatusBadRequest, "new.tmpl", withPage(page, gin.H{"Form": form, "Error": err.Error()}))
+			return
+		}
+
+		err = community.UpdateTopic(communityDir, topic.Filename, func(t *community.Topic) {
+			t.Title = form.Title
+			t.Body = form.Body
+			t.Tags = parseTags(form.Tags)
 		})
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				c.String(http.StatusNotFound, "topic not found: %s", ref)
+				return
+			}
+			c.HTML(http.StatusInternalServerError, "new.tmpl", withPage(page, gin.H{"Form": form, "Error": fmt.Sprintf("Saving the topic failed: %v", err)}))
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, topicURL(basePath, topic))
+	}
+
+	// deleteTopic removes a thread and returns to the index
+	deleteTopic := func(c *gin.Context, ref string) {
+		rel := ref
+		if !isTopicPath(ref) {
+			topic, err := community.LoadTopicByID(communityDir, ref)
+			if err != nil {
+				c.String(http.StatusNotFound, "topic not found: %s", ref)
+				return
+			}
+			rel = topic.Filename
+		}
+
+		if err := community.DeleteTopic(communityDir, rel); err != nil {
+			switch {
+			case errors.Is(err, fs.ErrNotExist):
+				c.String(http.StatusNotFound, "topic not found: %s", ref)
+			case errors.Is(err, community.ErrInvalidTopicPath):
+				c.String(http.StatusBadRequest, "%v", err)
+			default:
+				c.String(http.StatusInternalServerError, "failed to delete topic: %v", err)
+			}
+			return
+		}
+		c.Redirect(http.StatusSeeOther, basePath+"/")
+	}
+
+	// archiveTopic hides a thread from the listings, or brings it back, and
+	// returns to it
+	archiveTopic := func(c *gin.Context, ref string, archived bool) {
+		topic, err := loadTopicRef(communityDir, ref)
+		if err != nil {
+			c.String(http.StatusNotFound, "topic not found: %v", err)
+			return
+		}
+
+		change := community.ArchiveTopic
+		if !archived {
+			change = community.UnarchiveTopic
+		}
+		if err := change(communityDir, topic.Filename); err != nil {
+			c.String(http.StatusInternalServerError, "failed to update topic: %v", err)
+			return
+		}
+		c.Redirect(http.StatusSeeOther, topicURL(basePath, topic))
+	}
+
+	// Gin cannot route segments after a catch-all, so actions on a topic are
+	// dispatched on the path suffix
+	routes.POST("/topic/*topicPath", limitBody(opts.maxBodyBytes), func(c *gin.Context) {
+		rel := strings.TrimPrefix(c.Param("topicPath"), "/")
+		switch {
+		case strings.HasSuffix(rel, "/reply"):
+			postReply(c, strings.TrimSuffix(rel, "/reply"))
+		case strings.HasSuffix(rel, "/edit"):
+			editTopic(c, strings.TrimSuffix(rel, "/edit"))
+		case strings.HasSuffix(rel, "/delete"):
+			deleteTopic(c, strings.TrimSuffix(rel, "/delete"))
+		case strings.HasSuffix(rel, "/unarchive"):
+			archiveTopic(c, strings.TrimSuffix(rel, "/unarchive"), false)
+		case strings.HasSuffix(rel, "/archive"):
+			archiveTopic(c, strings.TrimSuffix(rel, "/archive"), true)
+		default:
+			c.String(http.StatusNotFound, "not found")
+		}
+	})
+
+	newTopicPage := gin.H{"Action": basePath + "/topics", "BackURL": basePath + "/"}
+
+	routes.GET("/new", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "new.tmpl", withPage(newTopicPage, gin.H{"Form": topicForm{}}))
+	})
+
+	routes.POST("/topics", limitBody(opts.maxBodyBytes), func(c *gin.Context) {
+		if err := c.Request.ParseForm(); err != nil {
+			c.HTML(bodyErrorStatus(err), "new.tmpl", withPage(newTopicPage, gin.H{"Form": topicForm{}, "Error": fmt.Sprintf("Invalid form submission: %v", err)}))
+			return
+		}
+
+		form := readTopicForm(c)
+		showError := func(status int, msg string) {
+			c.HTML(status, "new.tmpl", withPage(newTopicPage, gin.H{"Form": form, "Error": msg}))
+		}
+
+		if err := form.validate(); err != nil {
+			showError(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		author := form.Author
+		if author == "" {
+			author = defaultAuthor
+		}
+		topic, err := community.CreateTopic(communityDir, community.Topic{
+			Title:     form.Title,
+			Body:      form.Body,
+			Author:    author,
+			Timestamp: time.Now().Format(time.RFC3339),
+			Tags:      parseTags(form.Tags),
+			Replies:   []community.Reply{},
+		})
+		if err != nil {
+			showError(http.StatusInternalServerError, fmt.Sprintf("Saving the topic failed: %v", err))
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, topicURL(basePath, topic))
+	})
+
+	routes.GET("/agents", func(c *gin.Context) {
+		stats, err := community.AgentStats(communityDir)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "failed to load agent stats: %v", err)
+			return
+		}
+		c.HTML(http.StatusOK, "agents.tmpl", gin.H{"Agents": agentRows(agentList, stats, aliases)})
+	})
+
+	routes.GET("/author/:id", func(c *gin.Context) {
+		id := c.Param("id")
+		started, repliedTo, err := community.LoadTopicsByAuthor(communityDir, id)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "failed to load topics: %v", err)
+			return
+		}
+
+		c.HTML(http.StatusOK, "author.tmpl", gin.H{
+			"ID":        id,
+			"Name":      authorName(agentsByID, id),
+			"Style":     agentsByID[id].Style,
+			"Started":   summarizeTopics(started, aliases, agentsByID, basePath),
+			"RepliedTo": summarizeTopics(repliedTo, aliases, agentsByID, basePath),
+		})
+	})
+
+	// The author's topics and replies as RSS, for following one persona
+	routes.GET("/author/:id/feed.xml", func(c *gin.Context) {
+		id := c.Param("id")
+		started, repliedTo, err := community.LoadTopicsByAuthor(communityDir, id)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "failed to load topics: %v", err)
+			return
+		}
+
+		baseURL := requestBaseURL(c)
+		items := authorFeedItems(append(started, repliedTo...), id, baseURL, basePath)
+		channel := rssChannel{
+			Title:       authorName(agentsByID, id) + " · Kommunity",
+			Link:        baseURL + basePath + "/author/" + url.PathEscape(id),
+			Description: "Topics and replies by " + authorName(agentsByID, id),
+			Items:       items,
+		}
+		if len(items) > 0 {
+			channel.LastBuildDate = items[0].PubDate
+		}
+		writeRSS(c, rssFeed{Channel: channel})
+	})
+
+	registerAPIRoutes(routes, opts, agentList)
+	registerWebSocketRoute(routes)
+
+	// simulate/step lets a browser or script drive the community one agent
+	// turn at a time. It is only registered when the server was given a step.
+	if opts.step != nil {
+		routes.POST("/simulate/step", func(c *gin.Context) {
+			result, err := opts.step(c.Request.Context())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"agent":  result.Agent,
+					"action": result.Action,
+					"topic":  result.Topic,
+					"error":  err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusOK, result)
+		})
+	}
+
+	// Topic images; http.Dir keeps requests inside the directory
+	routes.Static("/images", opts.data.images())
+
+	routes.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	ollamaRunning := opts.ollamaRunning
+	if ollamaRunning == nil {
+		ollamaRunning = ollama.IsOllamaRunningContext
+	}
+	// healthz is for uptime checks: it only lists the community directory and
+	// answers 503 when that fails. An unreachable or stalled Ollama is
+	// reported but doesn't fail or hold up the check, since the viewer works
+	// without it.
+	routes.GET("/healthz", func(c *gin.Context) {
+		topics, err := community.CountTopicFiles(communityDir)
+		probeCtx, cancel := context.WithTimeout(c.Request.Context(), healthProbeTimeout)
+		defer cancel()
+		status, body := http.StatusOK, gin.H{
+			"community_readable": err == nil,
+			"topics":             topics,
+			"ollama_reachable":   ollamaRunning(probeCtx),
+		}
+		if err != nil {
+			status, body["error"] = http.StatusServiceUnavailable, err.Error()
+		}
+		c.JSON(status, body)
+	})
+
+	routes.GET("/events", func(c *gin.Context) {
+		events, unsubscribe := community.Subscribe()
+		defer unsubscribe()
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		ctx := c.Request.Context()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case ev := <-events:
+				c.SSEvent(ev.Type, ev)
+				return true
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				return true
+			}
+		})
+	})
+
+	return router, nil
+}
+
+// contentETag derives a weak ETag from everything a page is rendered from
+func contentETag(parts ...any) string {
+	sum := sha1.New()
+	if err := json.NewEncoder(sum).Encode(parts); err != nil {
+		return ""
+	}
+	return fmt.Sprintf(`W/"%x"`, sum.Sum(nil)[:10])
+}
+
+// notModified sets the ETag and Last-Modified validators and answers 304 when
+// the client's copy is still current. If-None-Match wins over
+// If-Modified-Since, as in RFC 9110.
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	c.Header("Cache-Control", "no-cache")
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if etag == "" || !etagMatches(match, etag) {
+			return false
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err != nil || modified.IsZero() || modified.Truncate(time.Second).After(since) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing
+// weakly
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// latest returns the later of two times
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// limitBody caps the request body so oversized writes fail fast with 413
+// instead of bloating the community directory.
+func limitBody(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if n > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+		}
+		c.Next()
+	}
+}
+
+// bodyErrorStatus maps a request body read/bind error to an HTTP status
+func bodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// checkLength rejects values longer than limit runes
+func checkLength(field, value string, limit int) error {
+	if n := utf8.RuneCountInString(value); n > limit {
+		return fmt.Errorf("%s is too long (%d characters, max %d)", field, n, limit)
+	}
+	return nil
+}
+
+// pageNumber parses a 1-based ?page= value, defaulting to the first page
+func pageNumber(raw string) int {
+	page, err := strconv.Atoi(raw)
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+// newPagination describes page out of the pages needed for total topics,
+// linking neighbours relative to listURL, which may already carry a query
+func newPagination(listURL string, page, total int) pagination {
+	p := pagination{Page: page, Pages: max(1, (total+topicsPerPage-1)/topicsPerPage)}
+	sep := "?"
+	if strings.Contains(listURL, "?") {
+		sep = "&"
+	}
+	if page > 1 {
+		p.PrevURL = fmt.Sprintf("%s%spage=%d", listURL, sep, min(page-1, p.Pages))
+	}
+	if page < p.Pages {
+		p.NextURL = fmt.Sprintf("%s%spage=%d", listURL, sep, page+1)
+	}
+	return p
+}
+
+// agentRows lists every configured agent, plus any other author with
+// activity, most active first. Activity recorded under an agent's display
+// name counts toward its ID.
+func agentRows(agentList []agents.Agent, stats map[string]community.AgentActivity, aliases map[string]string) []agentRow {
+	byID := make(map[string]*agentRow)
+	var rows []*agentRow
+	for _, a := range agentList {
+		row := &agentRow{ID: a.ID, Name: a.Name, Style: a.Style}
+		byID[a.ID] = row
+		rows = append(rows, row)
+	}
+
+	for author, activity := range stats {
+		id := community.NormalizeAuthor(author, aliases)
+		row, ok := byID[id]
+		if !ok {
+			row = &agentRow{ID: id, Name: id}
+			byID[id] = row
+			rows = append(rows, row)
+		}
+		row.Topics += activity.Topics
+		row.Replies += activity.Replies
+		row.Upvotes += activity.Upvotes
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		pi, pj := rows[i].Topics+rows[i].Replies, rows[j].Topics+rows[j].Replies
+		if pi != pj {
+			return pi > pj
+		}
+		return rows[i].ID < rows[j].ID
 	})
 
-	return router.Run(addr)
+	result := make([]agentRow, len(rows))
+	for i, row := range rows {
+		result[i] = *row
+	}
+	return result
+}
+
+// authorName returns the display name of the agent with the given ID, or the
+// ID itself for authors that aren't agents
+func authorName(agentsByID map[string]agents.Agent, id string) string {
+	if agent, ok := agentsByID[id]; ok && agent.Name != "" {
+		return agent.Name
+	}
+	return id
+}
+
+// summarizeTopics prepares topics for the index listing, keeping their order
+func summarizeTopics(topics []community.Topic, aliases map[string]string, agentsByID map[string]agents.Agent, basePath string) []topicSummary {
+	summaries := make([]topicSummary, 0, len(topics))
+	for _, t := range topics {
+		community.NormalizeAuthors(&t, aliases)
+		summaries = append(summaries, topicSummary{
+			Title:      t.Title,
+			Author:     t.Author,
+			AuthorName: authorName(agentsByID, t.Author),
+			Timestamp:  t.Timestamp,
+			When:       formatTime(t.Timestamp),
+			Snippet:    buildSnippet(t.Body),
+			Board:      t.Board,
+			Lang:       t.Lang,
+			Tags:       t.Tags,
+			ReplyCount: len(t.Replies),
+			Path:       topicURL(basePath, t),
+		})
+	}
+	return summaries
+}
+
+// readTopicForm reads the fields of the topic form from a parsed request
+func readTopicForm(c *gin.Context) topicForm {
+	return topicForm{
+		Title:  strings.TrimSpace(c.PostForm("title")),
+		Body:   strings.TrimSpace(c.PostForm("body")),
+		Author: strings.TrimSpace(c.PostForm("author")),
+		Tags:   c.PostForm("tags"),
+	}
+}
+
+// validate checks the required fields and length limits of a topic form
+func (f topicForm) validate() error {
+	if f.Title == "" || f.Body == "" {
+		return errors.New("title and body are required")
+	}
+	if err := checkLength("title", f.Title, maxTitleLength); err != nil {
+		return err
+	}
+	return checkLength("body", f.Body, maxContentLength)
+}
+
+// editTopicPage configures new.tmpl as the edit form for a topic
+func editTopicPage(basePath string, topic community.Topic) gin.H {
+	link := topicURL(basePath, topic)
+	return gin.H{"Action": link + "/edit", "BackURL": link, "Editing": true}
+}
+
+// withPage merges per-request template data into a page's fixed settings
+func withPage(page, data gin.H) gin.H {
+	merged := make(gin.H, len(page)+len(data))
+	for k, v := range page {
+		merged[k] = v
+	}
+	for k, v := range data {
+		merged[k] = v
+	}
+	return merged
+}
+
+// parseTags splits a comma-separated tag list, dropping blanks and
+// duplicates
+func parseTags(raw string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(raw, ",") {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+	return tags
 }
 
 func formatTime(ts string) string {
 	if ts == "" {
 		return ""
 	}
-	if parsed, err := time.Parse(time.RFC3339, ts); err == nil {
-		return parsed.Format("Jan 2, 2006 15:04 MST")
-	}
-	if parsed, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+	if parsed, err := community.ParseTimestamp(ts); err == nil {
 		return parsed.Format("Jan 2, 2006 15:04 MST")
 	}
 	return ts
 }
 
+// buildSnippet previews a topic body on the index as plain text of at most
+// 160 runes
 func buildSnippet(body string) string {
-	trimmed := strings.TrimSpace(body)
-	runes := []rune(trimmed)
+	text := stripMarkdown(body)
+	runes := []rune(text)
 	if len(runes) <= 160 {
-		return trimmed
+		return text
+	}
+	cut := string(runes[:157])
+	// Break at the last space so no word is cut in half, unless a single
+	// very long word fills most of the snippet
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:-") + "..."
+}
+
+// topicURL links to a topic's page by its ID, falling back to its file path
+func topicURL(basePath string, topic community.Topic) string {
+	if topic.ID == "" {
+		return toURLPath(basePath, topic.Filename)
 	}
-	return string(runes[:157]) + "..."
+	return basePath + "/topic/" + url.PathEscape(topic.ID)
 }
 
-func toURLPath(rel string) string {
+// isTopicPath reports whether ref names a topic file rather than a topic ID
+func isTopicPath(ref string) bool {
+	return strings.HasSuffix(strings.ToLower(ref), ".json")
+}
+
+// loadTopicRef loads a topic given either its ID or its path relative to the
+// community directory. Paths keep links from before topic IDs working.
+func loadTopicRef(dir, ref string) (community.Topic, error) {
+	if isTopicPath(ref) {
+		return community.LoadTopicByRelativePath(dir, ref)
+	}
+	return community.LoadTopicByID(dir, ref)
+}
+
+func toURLPath(basePath, rel string) string {
 	if rel == "" {
 		return ""
 	}
-	return "/topic/" + strings.TrimPrefix(filepath.ToSlash(rel), "/")
+	return basePath + "/topic/" + strings.TrimPrefix(filepath.ToSlash(rel), "/")
+}
+
+// normalizeBasePath turns a user-supplied prefix such as "kommunity/" into
+// "/kommunity". The root path normalizes to the empty string.
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
 }
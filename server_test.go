@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"kommunity/community"
+	"kommunity/ollama"
+)
+
+// newTestRouter serves a fresh, empty data directory
+func newTestRouter(t *testing.T, opts serverOptions) (*gin.Engine, dataPaths) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	opts.data = dataPaths{root: t.TempDir()}
+	if err := os.MkdirAll(opts.data.community(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	router, err := newRouter(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router, opts.data
+}
+
+func TestEventsStreamsNewTopics(t *testing.T) {
+	router, paths := newTestRouter(t, serverOptions{})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// The handler has subscribed once the headers are flushed
+	_, err = community.CreateTopic(paths.community(), community.Topic{Title: "Streamed", Body: "Live", Author: "alice", Timestamp: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	timeout := time.After(5 * time.Second)
+	var event string
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream ended before the event arrived")
+			}
+			if strings.HasPrefix(line, "event:") {
+				event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			}
+			if strings.HasPrefix(line, "data:") && event == community.EventTopicCreated {
+				if !strings.Contains(line, `"Streamed"`) {
+					t.Errorf("data = %q, want the new topic's title", line)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("no topic event within 5s")
+		}
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	for in, want := range map[string]string{
+		"":             "",
+		"/":            "",
+		"kommunity":    "/kommunity",
+		"/kommunity/":  "/kommunity",
+		" kommunity/ ": "/kommunity",
+		"/a/b/":        "/a/b",
+	} {
+		if got := normalizeBasePath(in); got != want {
+			t.Errorf("normalizeBasePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBasePathPrefixesRoutesAndLinks(t *testing.T) {
+	router, paths := newTestRouter(t, serverOptions{basePath: "/kommunity/"})
+	topic, err := community.CreateTopic(paths.community(), community.Topic{Title: "Proxied", Body: "Behind nginx", Author: "alice", Timestamp: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := serve(router, "/kommunity/")
+	if index.Code != http.StatusOK {
+		t.Fatalf("GET /kommunity/ = %d, want 200", index.Code)
+	}
+	link := `href="/kommunity/topic/` + topic.ID + `"`
+	if !strings.Contains(index.Body.String(), link) {
+		t.Errorf("index has no %s", link)
+	}
+
+	page := serve(router, "/kommunity/topic/"+topic.ID)
+	if page.Code != http.StatusOK {
+		t.Fatalf("GET /kommunity/topic/%s = %d, want 200", topic.ID, page.Code)
+	}
+	if !strings.Contains(page.Body.String(), `action="/kommunity/topic/`+topic.ID+`/reply#reply"`) {
+		t.Error("reply form doesn't post under the base path")
+	}
+
+	if rec := serve(router, "/topic/"+topic.ID); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /topic/%s without the base path = %d, want 404", topic.ID, rec.Code)
+	}
+}
+
+func TestEvictedTopicsAreListedAsArchived(t *testing.T) {
+	router, paths := newTestRouter(t, serverOptions{})
+	old, err := community.CreateTopic(paths.community(), community.Topic{Title: "Stale thread", Body: "Quiet", Author: "alice", Timestamp: "2024-01-01T00:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := community.CreateTopic(paths.community(), community.Topic{Title: "Fresh thread", Body: "Busy", Author: "bob", Timestamp: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived, err := community.EvictToLimit(paths.community(), 1); err != nil || archived != 1 {
+		t.Fatalf("EvictToLimit = %d, %v; want 1 archived", archived, err)
+	}
+
+	link := func(topic community.Topic) string { return `href="/topic/` + topic.ID + `"` }
+	index := serve(router, "/").Body.String()
+	if strings.Contains(index, link(old)) || !strings.Contains(index, link(fresh)) {
+		t.Error("index should list only the topic that wasn't evicted")
+	}
+	archive := serve(router, "/?archived=true").Body.String()
+	if !strings.Contains(archive, link(old)) {
+		t.Error("evicted topic is missing from /?archived=true")
+	}
+	if rec := serve(router, "/topic/"+old.ID); rec.Code != http.StatusOK {
+		t.Errorf("GET /topic/%s = %d, want the evicted topic's page", old.ID, rec.Code)
+	}
+
+	if err := community.UnarchiveTopic(paths.community(), old.Filename); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(serve(router, "/").Body.String(), link(old)) {
+		t.Error("unarchived topic is missing from the index")
+	}
+}
+
+func TestHealthzDoesNotWaitForStalledOllama(t *testing.T) {
+	stall := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stall:
+		case <-r.Context().Done():
+		}
+	}))
+	defer stalled.Close()
+	defer close(stall)
+
+	client := ollama.NewClient(stalled.URL)
+	router, _ := newTestRouter(t, serverOptions{ollamaRunning: client.IsRunningContext})
+
+	start := time.Now()
+	rec := serve(router, "/healthz")
+	if elapsed := time.Since(start); elapsed > healthProbeTimeout+time.Second {
+		t.Errorf("/healthz took %s with a stalled Ollama", elapsed)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /healthz = %d, want 200", rec.Code)
+	}
+	var body struct {
+		CommunityReadable bool `json:"community_readable"`
+		OllamaReachable   bool `json:"ollama_reachable"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if !body.CommunityReadable || body.OllamaReachable {
+		t.Errorf("/healthz = %s, want a readable community and an unreachable Ollama", rec.Body)
+	}
+}
+
+// serve answers a GET for path
+func serve(router http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"kommunity/community"
+	"kommunity/ollama"
+)
+
+// Limits on the thread summary cache
+const (
+	maxCachedSummaries = 256             // least recently used entries beyond this are dropped
+	summaryTimeout     = 5 * time.Minute // bounds a generation no request can cancel
+)
+
+// threadSummaries caches the summaries served by GET /topic/<id>/summary,
+// keyed by topic file. A summary is reused until the thread's fingerprint
+// changes, so new replies and edits regenerate it but votes don't.
+type threadSummaries struct {
+	mu       sync.Mutex
+	byTopic  map[string]threadSummary
+	inFlight map[string]*summaryCall
+	uses     uint64 // counts cache hits and fills, ordering entries by use
+}
+
+type threadSummary struct {
+	fingerprint string
+	text        string
+	used        uint64 // value of uses when last read or stored
+}
+
+// summaryCall is a generation in progress; done is closed once text and err
+// are set
+type summaryCall struct {
+	fingerprint string
+	done        chan struct{}
+	text        string
+	err         error
+}
+
+func newThreadSummaries() *threadSummaries {
+	return &threadSummaries{
+		byTopic:  make(map[string]threadSummary),
+		inFlight: make(map[string]*summaryCall),
+	}
+}
+
+// get returns the cached summary for topic, or generates and caches a new one.
+// Concurrent misses for the same thread share one generation, which runs
+// detached from the requests waiting on it: a caller whose ctx ends stops
+// waiting, but the summary is still cached for the others. cached reports
+// whether the model was skipped.
+func (t *threadSummaries) get(ctx context.Context, topic community.Topic, generate func(context.Context) (string, error)) (text string, cached bool, err error) {
+	fingerprint := threadFingerprint(topic)
+
+	t.mu.Lock()
+	if entry, ok := t.byTopic[topic.Filename]; ok && entry.fingerprint == fingerprint {
+		t.uses++
+		entry.used = t.uses
+		t.byTopic[topic.Filename] = entry
+		t.mu.Unlock()
+		return entry.text, true, nil
+	}
+	call, ok := t.inFlight[topic.Filename]
+	if !ok || call.fingerprint != fingerprint {
+		call = &summaryCall{fingerprint: fingerprint, done: make(chan struct{})}
+		t.inFlight[topic.Filename] = call
+		go t.generate(context.WithoutCancel(ctx), topic.Filename, call, generate)
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.text, false, call.err
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+}
+
+// generate runs call and caches its summary under filename
+func (t *threadSummaries) generate(ctx context.Context, filename string, call *summaryCall, generate func(context.Context) (string, error)) {
+	ctx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+	if call.text, call.err = generate(ctx); call.err != nil {
+		call.text = ""
+	}
+
+	t.mu.Lock()
+	if call.err == nil {
+		t.uses++
+		t.byTopic[filename] = threadSummary{fingerprint: call.fingerprint, text: call.text, used: t.uses}
+		t.evict()
+	}
+	if t.inFlight[filename] == call {
+		delete(t.inFlight, filename)
+	}
+	t.mu.Unlock()
+	close(call.done)
+}
+
+// evict drops the least recently used summaries beyond maxCachedSummaries,
+// so topics that were deleted or renamed don't stay cached forever. t.mu
+// must be held.
+func (t *threadSummaries) evict() {
+	for len(t.byTopic) > maxCachedSummaries {
+		var oldest string
+		var oldestUsed uint64
+		for filename, entry := range t.byTopic {
+			if oldest == "" || entry.used < oldestUsed {
+				oldest, oldestUsed = filename, entry.used
+			}
+		}
+		delete(t.byTopic, oldest)
+	}
+}
+
+// threadFingerprint hashes the parts of a thread a summary is written from
+func threadFingerprint(topic community.Topic) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", topic.Title, topic.Body)
+	for _, reply := range topic.Replies {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", reply.ID, reply.Author, reply.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// summarizeThread asks the model for a short bullet-point digest of a whole
+// thread. It uses the reply prompt's context builder with every reply
+// included, still held to the -context-chars budget.
+func (s *simulator) summarizeThread(ctx context.Context, gen Generator, topic community.Topic) (string, error) {
+	opts := s.replyContext
+	opts.summarizeOld, opts.maxReplies = false, 0
+	thread := s.buildThreadContext(ctx, gen, &topic, opts)
+
+	prompt := fmt.Sprintf("Summarize this discussion in 3 bullet points, noting who argued what. Reply with only the bullet points, each starting with \"- \".\n\n%s", thread)
+	summary, err := s.complete(ctx, gen, "", "", prompt, ollama.Options{})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"kommunity/community"
+)
+
+func TestThreadSummariesShareConcurrentGeneration(t *testing.T) {
+	cache := newThreadSummaries()
+	topic := community.Topic{Filename: "t.json", Title: "Tea", Body: "Green or black?"}
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	generate := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "- tea", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			text, _, err := cache.get(context.Background(), topic, generate)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = text
+		}(i)
+	}
+	// Let the first caller start generating before releasing it
+	for calls.Load() == 0 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("model called %d times, want 1", n)
+	}
+	for i, text := range results {
+		if text != "- tea" {
+			t.Errorf("caller %d got %q", i, text)
+		}
+	}
+
+	if _, cached, _ := cache.get(context.Background(), topic, generate); !cached {
+		t.Error("summary was not cached")
+	}
+	topic.Replies = append(topic.Replies, community.Reply{Author: "bob", Content: "Oolong"})
+	if _, cached, _ := cache.get(context.Background(), topic, generate); cached {
+		t.Error("summary was reused after a new reply")
+	}
+}
+
+func TestThreadSummariesOutliveTheFirstRequest(t *testing.T) {
+	cache := newThreadSummaries()
+	topic := community.Topic{Filename: "t.json", Title: "Knives"}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	generate := func(ctx context.Context) (string, error) {
+		close(started)
+		select {
+		case <-release:
+			return "- knives", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	// The first request gives up while the model is still writing
+	first, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, _, err := cache.get(first, topic, generate)
+		firstDone <- err
+	}()
+	<-started
+	cancel()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled request got %v, want context.Canceled", err)
+	}
+
+	waiter := make(chan string, 1)
+	go func() {
+		text, _, err := cache.get(context.Background(), topic, generate)
+		if err != nil {
+			t.Error(err)
+		}
+		waiter <- text
+	}()
+	close(release)
+	if text := <-waiter; text != "- knives" {
+		t.Errorf("waiting request got %q, want the shared summary", text)
+	}
+}
+
+func TestThreadSummariesStayBounded(t *testing.T) {
+	cache := newThreadSummaries()
+	generate := func(ctx context.Context) (string, error) { return "- summary", nil }
+	for i := 0; i < maxCachedSummaries+10; i++ {
+		topic := community.Topic{Filename: fmt.Sprintf("%d.json", i), Title: "Topic"}
+		if _, _, err := cache.get(context.Background(), topic, generate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(cache.byTopic); n != maxCachedSummaries {
+		t.Errorf("cache holds %d summaries, want %d", n, maxCachedSummaries)
+	}
+	if _, ok := cache.byTopic["0.json"]; ok {
+		t.Error("least recently used summary was kept")
+	}
+}
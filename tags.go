@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kommunity/ollama"
+)
+
+// maxGeneratedTags caps how many tags are kept from the model's answer
+const maxGeneratedTags = 4
+
+// generateTags asks the model for a few topical tags for a new topic,
+// preferring the community's configured tags when they fit
+func (s *simulator) generateTags(ctx context.Context, gen Generator, topic string) ([]string, error) {
+	prompt := fmt.Sprintf("Suggest 2-4 short topical tags for this discussion topic. Answer with the tags only, separated by commas.\n\nTopic: %s", topic)
+	if len(s.tagVocabulary) > 0 {
+		prompt += fmt.Sprintf("\n\nPrefer these existing tags when they fit: %s", strings.Join(s.tagVocabulary, ", "))
+	}
+
+	output, err := s.complete(ctx, gen, "", "", prompt, ollama.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("generating tags: %w", err)
+	}
+	return parseGeneratedTags(output), nil
+}
+
+// tagPrefix matches a "Tags:" label, list bullet, or hash before a tag
+var tagPrefix = regexp.MustCompile(`^(?:tags?:\s*)?(?:[-*•]|\d+[.)])?\s*#?`)
+
+// parseGeneratedTags reads tags separated by commas or newlines, tolerating
+// list bullets, hashes, and quotes. Tags are lowercased, inner spaces become
+// hyphens, and duplicates and anything too long to be a tag are dropped.
+func parseGeneratedTags(output string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(output, func(r rune) bool { return r == ',' || r == '\n' || r == ';' }) {
+		tag := strings.ToLower(strings.TrimSpace(field))
+		tag = tagPrefix.ReplaceAllString(tag, "")
+		tag = strings.Trim(tag, "\"'`.")
+		tag = strings.Join(strings.Fields(tag), "-")
+		if tag == "" || len(tag) > 30 || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxGeneratedTags {
+			break
+		}
+	}
+	return tags
+}
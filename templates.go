@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/render"
+)
+
+// templateSet renders the HTML views and can reload them from disk while the
+// server is running. It implements gin's render.HTMLRender.
+type templateSet struct {
+	pattern string
+	funcs   template.FuncMap
+
+	mu   sync.RWMutex
+	tmpl *template.Template
+}
+
+func newTemplateSet(pattern string, funcs template.FuncMap) (*templateSet, error) {
+	t := &templateSet{pattern: pattern, funcs: funcs}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// load parses the templates and swaps them in. On error the previous set
+// stays active.
+func (t *templateSet) load() error {
+	tmpl, err := template.New("").Funcs(t.funcs).ParseGlob(t.pattern)
+	if err != nil {
+		return fmt.Errorf("parsing templates: %w", err)
+	}
+
+	t.mu.Lock()
+	t.tmpl = tmpl
+	t.mu.Unlock()
+	return nil
+}
+
+// Instance implements render.HTMLRender
+func (t *templateSet) Instance(name string, data any) render.Render {
+	t.mu.RLock()
+	tmpl := t.tmpl
+	t.mu.RUnlock()
+
+	return render.HTML{Template: tmpl, Name: name, Data: data}
+}
+
+// watch starts polling dirs in the background and reloads the templates
+// whenever a file in them is added, removed, or modified.
+func (t *templateSet) watch(dirs []string, interval time.Duration) {
+	last := snapshot(dirs)
+	go func() {
+		for range time.Tick(interval) {
+			current := snapshot(dirs)
+			if current == last {
+				continue
+			}
+			last = current
+
+			if err := t.load(); err != nil {
+				log.Printf("dev: template reload failed: %v", err)
+				continue
+			}
+			log.Printf("dev: reloaded templates from %s", t.pattern)
+		}
+	}()
+}
+
+// snapshot summarizes the files under dirs so changes can be detected
+// cheaply. Missing directories are ignored.
+func snapshot(dirs []string) string {
+	var files int
+	var latest time.Time
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			files++
+			if info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+			return nil
+		})
+	}
+	return fmt.Sprintf("%d:%d", files, latest.UnixNano())
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"html/template"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// renderPage renders the page.tmpl template from set
+func renderPage(t *testing.T, set *templateSet) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	if err := set.Instance("page.tmpl", nil).Render(rec); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Body.String()
+}
+
+func TestTemplateSetReloadsChangedTemplates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.tmpl")
+	if err := os.WriteFile(path, []byte("version one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := newTemplateSet(filepath.Join(dir, "*.tmpl"), template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderPage(t, set); got != "version one" {
+		t.Fatalf("rendered %q, want %q", got, "version one")
+	}
+	set.watch([]string{dir}, 10*time.Millisecond)
+
+	if err := os.WriteFile(path, []byte("version two"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Coarse file system clocks could leave the modification time unchanged
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for renderPage(t, set) != "version two" {
+		if time.Now().After(deadline) {
+			t.Fatalf("still rendering %q after the template changed", renderPage(t, set))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTemplateSetKeepsWorkingSetOnParseError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.tmpl")
+	if err := os.WriteFile(path, []byte("good"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := newTemplateSet(filepath.Join(dir, "*.tmpl"), template.FuncMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("{{ .Broken "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.load(); err == nil {
+		t.Fatal("load succeeded on a broken template")
+	}
+	if got := renderPage(t, set); got != "good" {
+		t.Errorf("rendered %q, want the previous template", got)
+	}
+}
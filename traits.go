@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+
+	"kommunity/agents"
+	"kommunity/community"
+)
+
+// Traits run from 0 to 1 with 0.5 as neutral. Each mapping below is a pure
+// function of the trait so its effect can be checked in isolation.
+
+// courageCreateFactor scales the chance of starting a topic: half as likely
+// for the most cautious agent, 1.5 times as likely for the boldest
+func courageCreateFactor(courage float64) float64 {
+	return 0.5 + clampTrait(courage)
+}
+
+// empathyUpvoteFactor scales the chance of upvoting instead of replying:
+// half as likely for the least empathetic agent, 1.5 times for the most
+func empathyUpvoteFactor(empathy float64) float64 {
+	return 0.5 + clampTrait(empathy)
+}
+
+// empathyReplyWeight weighs a topic with the given number of replies when an
+// agent picks a thread to answer. Empathy 0 treats every topic alike; higher
+// empathy favors threads nobody has answered yet.
+func empathyReplyWeight(empathy float64, replies int) float64 {
+	return math.Pow(1/float64(1+replies), clampTrait(empathy))
+}
+
+// traitGuidance turns clearly high or low traits into tone instructions for
+// the persona. Middling traits add nothing.
+func traitGuidance(agent agents.Agent) string {
+	var hints []string
+	add := func(trait float64, low, high string) {
+		switch {
+		case trait <= 0.35:
+			hints = append(hints, low)
+		case trait >= 0.65:
+			hints = append(hints, high)
+		}
+	}
+	add(agent.Courage, "You are cautious and hedge your opinions.", "You state bold, even contrarian opinions.")
+	add(agent.Empathy, "You focus on facts rather than feelings.", "You acknowledge what others said and respond warmly.")
+	add(agent.Elegance, "You write plainly and casually.", "You write with polish and precise word choice.")
+	return strings.Join(hints, " ")
+}
+
+// pickReplyTopic chooses a topic to reply to, weighted by the agent's empathy
+func pickReplyTopic(rng *rand.Rand, agent agents.Agent, topics []community.Topic) community.Topic {
+	weights := make([]float64, len(topics))
+	total := 0.0
+	for i, t := range topics {
+		weights[i] = empathyReplyWeight(agent.Empathy, len(t.Replies))
+		total += weights[i]
+	}
+
+	r := rng.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return topics[i]
+		}
+		r -= w
+	}
+	return topics[len(topics)-1]
+}
+
+func clampTrait(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kommunity/agents"
+	"kommunity/community"
+)
+
+// nonAgentAuthors may author topics without being agents: the seed topics in
+// config.json, and web posts whose author was left blank
+var nonAgentAuthors = map[string]bool{"seed": true, defaultAuthor: true}
+
+// validator collects the problems found in a data directory
+type validator struct {
+	problems int
+}
+
+// fail reports one problem with what, e.g. a file name
+func (v *validator) fail(what string, err error) {
+	v.problems++
+	fmt.Printf("   ❌ %s: %v\n", what, err)
+}
+
+// validateData loads agents, config, prompts, and every topic the way a run
+// would, printing each problem with the file it was found in, then a summary.
+// It returns the number of problems found.
+func validateData(paths dataPaths) int {
+	var v validator
+	fmt.Printf("🔎 Validating %s\n", paths.root)
+
+	agentsSource := filepath.Base(paths.agents())
+	if info, err := os.Stat(paths.agentsDir()); err == nil && info.IsDir() {
+		agentsSource = filepath.Base(paths.agentsDir()) + "/"
+	}
+	agentList, err := paths.loadAgents()
+	var invalid *agents.InvalidAgentsError
+	switch {
+	case errors.As(err, &invalid):
+		for _, problem := range invalid.Unwrap() {
+			v.fail(agentsSource, problem)
+		}
+	case err != nil:
+		v.fail(agentsSource, err)
+	}
+	known := agents.IDIndex(agentList)
+	aliases := agents.NameIndex(agentList)
+
+	// checkAuthor reports authors that aren't a loaded agent's ID, pointing
+	// out the ones -normalize-authors can fix. Without agents there is
+	// nothing to compare against.
+	checkAuthor := func(what, author string) {
+		if agentList == nil {
+			return
+		}
+		if _, ok := known[author]; ok || nonAgentAuthors[author] {
+			return
+		}
+		if id := community.NormalizeAuthor(author, aliases); id != author {
+			v.fail(what, fmt.Errorf("author %q is an agent's name rather than its ID %q (see -normalize-authors)", author, id))
+			return
+		}
+		v.fail(what, fmt.Errorf("author %q is not a known agent", author))
+	}
+
+	config, err := community.LoadConfig(paths.config())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		fmt.Printf("   ⚠️  No %s; only needed to seed a new community\n", filepath.Base(paths.config()))
+	case err != nil:
+		v.fail(filepath.Base(paths.config()), err)
+	}
+	for i, seed := range config.SeedTopics {
+		what := fmt.Sprintf("%s seed topic %d", filepath.Base(paths.config()), i+1)
+		if strings.TrimSpace(seed.Title) == "" {
+			v.fail(what, errors.New("empty title"))
+		}
+		checkAuthor(what, seed.Author)
+	}
+
+	if _, err := loadPrompts(paths.prompts()); err != nil {
+		v.fail(filepath.Base(paths.prompts()), err)
+	}
+
+	topics, skipped, err := community.LoadTopicsChecked(paths.community())
+	if err != nil {
+		v.fail("community", err)
+	}
+	// Topic files are named as in the data directory, e.g. community/x.json
+	topicLabel := func(rel string) string {
+		return filepath.ToSlash(filepath.Join(filepath.Base(paths.community()), rel))
+	}
+	for _, file := range skipped {
+		v.fail(topicLabel(file.Path), file.Err)
+	}
+
+	// File names must be unique across subdirectories too, ignoring case,
+	// or they clash on case-insensitive file systems and in flat exports
+	names := make(map[string]string, len(topics))
+	ids := make(map[string]string, len(topics))
+	for _, topic := range topics {
+		what := topicLabel(topic.Filename)
+		if strings.TrimSpace(topic.Title) == "" {
+			v.fail(what, errors.New("empty title"))
+		}
+		checkAuthor(what, topic.Author)
+
+		name := strings.ToLower(filepath.Base(topic.Filename))
+		if first, ok := names[name]; ok {
+			v.fail(what, fmt.Errorf("duplicate file name, also used by %s", first))
+		} else {
+			names[name] = what
+		}
+		if first, ok := ids[topic.ID]; ok {
+			v.fail(what, fmt.Errorf("duplicate id %q, also used by %s", topic.ID, first))
+		} else {
+			ids[topic.ID] = what
+		}
+	}
+
+	fmt.Printf("📋 Checked %d agents, %d seed topics, and %d topic files: %d problems\n",
+		len(agentList), len(config.SeedTopics), len(topics)+len(skipped), v.problems)
+	return v.problems
+}
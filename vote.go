@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"kommunity/agents"
+	"kommunity/community"
+	"kommunity/ollama"
+)
+
+// upvoteTopic has the agent read the recent topics and upvote the one it
+// finds most relevant to its persona. It costs one short generation and adds
+// no text to the community.
+func (s *simulator) upvoteTopic(ctx context.Context, gen Generator, agent agents.Agent, topics []community.Topic) (string, error) {
+	var list strings.Builder
+	for i, t := range topics {
+		fmt.Fprintf(&list, "%d. %s\n", i+1, t.Title)
+	}
+	prompt := fmt.Sprintf("Pick the discussion topic below that you find most interesting and relevant to you. Answer with its number only.\n\n%s", list.String())
+
+	answer, err := s.complete(ctx, gen, "", persona(agent), prompt, ollama.Options{})
+	if err != nil {
+		return "", fmt.Errorf("choosing a topic to upvote: %w", err)
+	}
+	choice, ok := parseChoice(answer, len(topics))
+	if !ok {
+		fmt.Printf("   🤷 No usable topic number in %q, skipping the vote\n", strings.TrimSpace(answer))
+		return "", nil
+	}
+
+	topic := topics[choice-1]
+	if err := community.UpvoteTopic(s.communityDir, topic.Filename); err != nil {
+		return "", fmt.Errorf("upvoting topic: %w", err)
+	}
+	s.session.votes.Add(1)
+	fmt.Printf("   👍 Upvoted '%s'\n", topic.Title[:min(50, len(topic.Title))]+"...")
+	return filepath.ToSlash(topic.Filename), nil
+}
+
+// parseChoice returns the first whole number in the model's answer if it
+// is between 1 and n
+func parseChoice(answer string, n int) (int, bool) {
+	fields := strings.FieldsFunc(answer, func(r rune) bool { return !unicode.IsDigit(r) })
+	if len(fields) == 0 {
+		return 0, false
+	}
+	choice, err := strconv.Atoi(fields[0])
+	if err != nil || choice < 1 || choice > n {
+		return 0, false
+	}
+	return choice, true
+}
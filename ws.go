@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"kommunity/community"
+)
+
+// maxWebSockets caps the live feed connections open at once; further clients
+// get 503 and can fall back to refreshing
+const maxWebSockets = 100
+
+// wsWriteTimeout bounds a single push, so a stalled client can't hold its
+// handler forever
+const wsWriteTimeout = 10 * time.Second
+
+// registerWebSocketRoute adds GET /ws, which pushes every community event as a
+// JSON message until the client goes away or the server shuts down.
+func registerWebSocketRoute(routes *gin.RouterGroup) {
+	var open atomic.Int64
+
+	feed := websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		events, unsubscribe := community.Subscribe()
+		defer unsubscribe()
+
+		// The feed is one-way; reading only tells us when the client leaves
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			var discard []byte
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+
+		ctx := ws.Request().Context()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-gone:
+				return
+			case ev := <-events:
+				ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				if err := websocket.JSON.Send(ws, ev); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	routes.GET("/ws", func(c *gin.Context) {
+		if open.Add(1) > maxWebSockets {
+			open.Add(-1)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "too many live connections"})
+			return
+		}
+		defer open.Add(-1)
+		feed.ServeHTTP(c.Writer, c.Request)
+	})
+}